@@ -1,8 +1,13 @@
 package main
 
 import (
+	"flag"
+	"os"
+	"strings"
+
 	"github.com/FlyKarlik/effectiveMobile/config"
 	"github.com/FlyKarlik/effectiveMobile/internal/app/users"
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
 	validator "github.com/FlyKarlik/effectiveMobile/pkg/validation"
 )
@@ -37,7 +42,61 @@ func main() {
 	}
 
 	usersApp := users.New(logger, cfg)
+
+	if len(os.Args) > 1 && os.Args[1] == "enrich" {
+		input, detach := parseEnrichArgs(os.Args[2:])
+		if err := usersApp.Enrich(input, detach); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	if err := usersApp.Start(); err != nil {
 		panic(err)
 	}
 }
+
+// parseEnrichArgs reads the flags of the enrich subcommand:
+//
+//	users-service enrich [-force] [-detach] [-status failed] [-name Ivan] ...
+//	users-service enrich -all [-force] [-detach]
+//
+// The filters mean the same as the query parameters of GET /v1/users. At
+// least one of them is required unless -all is given.
+func parseEnrichArgs(args []string) (domain.EnrichUsersInput, bool) {
+	flags := flag.NewFlagSet("enrich", flag.ExitOnError)
+
+	force := flags.Bool("force", false, "overwrite attributes that are already set")
+	detach := flags.Bool("detach", false, "only queue the users and leave them to the running service")
+	all := flags.Bool("all", false, "queue every user when no filter is given")
+	name := flags.String("name", "", "name filter (partial match)")
+	surname := flags.String("surname", "", "surname filter (partial match)")
+	nationality := flags.String("nationality", "", "comma-separated nationality filter")
+	sexSource := flags.String("sex-source", "", "sex source filter: PROVIDER, RULES or MANUAL")
+	status := flags.String("status", "", "enrichment status filter: pending, done or failed")
+
+	if err := flags.Parse(args); err != nil {
+		panic(err)
+	}
+
+	filter := domain.UserFilter{}
+	if *name != "" {
+		filter.Name = name
+	}
+	if *surname != "" {
+		filter.Surname = surname
+	}
+	for _, value := range strings.Split(*nationality, ",") {
+		if value = strings.ToUpper(strings.TrimSpace(value)); value != "" {
+			filter.Nationalities = append(filter.Nationalities, value)
+		}
+	}
+	if *sexSource != "" {
+		filter.SexSource = (*domain.SexSourceEnum)(sexSource)
+	}
+	if *status != "" {
+		filter.EnrichmentStatus = (*domain.EnrichmentStatusEnum)(status)
+	}
+
+	return domain.EnrichUsersInput{Filter: filter, Force: *force, All: *all}, *detach
+}
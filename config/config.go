@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/ilyakaznacheev/cleanenv"
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -15,6 +17,14 @@ type AppUsers struct {
 	LogLevel string `env:"APP__USERS__LOG_LEVEL" validate:"required,oneof=debug info warn error"`
 	AppPort  string `env:"APP__USERS__PORT" validate:"required,numeric,min=4,max=5"`
 	AppHost  string `env:"APP__USERS__HOST" validate:"required,hostname_rfc1123|ipv4|ipv6"`
+
+	// CursorSecret signs pagination cursors. Leave it empty to use a random
+	// key, at the cost of cursors not surviving restarts or load balancing.
+	CursorSecret string `env:"APP__USERS__CURSOR_SECRET" validate:"omitempty,min=16"`
+
+	// SearchSimilarityThreshold is the lowest trigram word similarity, from 0
+	// to 1, at which a user still matches the full-name search.
+	SearchSimilarityThreshold float64 `env:"APP__USERS__SEARCH_SIMILARITY_THRESHOLD" env-default:"0.5" validate:"gt=0,lte=1"`
 }
 
 type AppMigrator struct {
@@ -23,7 +33,9 @@ type AppMigrator struct {
 }
 
 type Infrastructure struct {
-	Postgres PostgreSQL `validate:"required"`
+	Postgres   PostgreSQL `validate:"required"`
+	HTTPClient HTTPClient `validate:"required"`
+	Enrichment Enrichment `validate:"required"`
 }
 
 type PostgreSQL struct {
@@ -35,6 +47,73 @@ type PostgreSQL struct {
 	ConnStr  string `env:"INFRA__POSTGRES__CONN_STR" validate:"required,url"`
 }
 
+type HTTPClient struct {
+	Timeout               time.Duration `env:"INFRA__HTTP_CLIENT__TIMEOUT" env-default:"10s" validate:"required"`
+	DialTimeout           time.Duration `env:"INFRA__HTTP_CLIENT__DIAL_TIMEOUT" env-default:"3s" validate:"required"`
+	KeepAlive             time.Duration `env:"INFRA__HTTP_CLIENT__KEEP_ALIVE" env-default:"30s" validate:"required"`
+	TLSHandshakeTimeout   time.Duration `env:"INFRA__HTTP_CLIENT__TLS_HANDSHAKE_TIMEOUT" env-default:"5s" validate:"required"`
+	ResponseHeaderTimeout time.Duration `env:"INFRA__HTTP_CLIENT__RESPONSE_HEADER_TIMEOUT" env-default:"5s" validate:"required"`
+	IdleConnTimeout       time.Duration `env:"INFRA__HTTP_CLIENT__IDLE_CONN_TIMEOUT" env-default:"90s" validate:"required"`
+	MaxIdleConns          int           `env:"INFRA__HTTP_CLIENT__MAX_IDLE_CONNS" env-default:"100" validate:"required,min=1"`
+	MaxIdleConnsPerHost   int           `env:"INFRA__HTTP_CLIENT__MAX_IDLE_CONNS_PER_HOST" env-default:"10" validate:"required,min=1"`
+}
+
+type Enrichment struct {
+	AgifyURL          string `env:"INFRA__ENRICHMENT__AGIFY_URL" env-default:"https://api.agify.io" validate:"required,http_url"`
+	AgifyAPIKey       string `env:"INFRA__ENRICHMENT__AGIFY_APIKEY" validate:"omitempty,printascii"`
+	GenderizeURL      string `env:"INFRA__ENRICHMENT__GENDERIZE_URL" env-default:"https://api.genderize.io" validate:"required,http_url"`
+	GenderizeAPIKey   string `env:"INFRA__ENRICHMENT__GENDERIZE_APIKEY" validate:"omitempty,printascii"`
+	NationalizeURL    string `env:"INFRA__ENRICHMENT__NATIONALIZE_URL" env-default:"https://api.nationalize.io" validate:"required,http_url"`
+	NationalizeAPIKey string `env:"INFRA__ENRICHMENT__NATIONALIZE_APIKEY" validate:"omitempty,printascii"`
+
+	RetryMaxAttempts int           `env:"INFRA__ENRICHMENT__RETRY_MAX_ATTEMPTS" env-default:"3" validate:"required,min=1,max=10"`
+	RetryBaseDelay   time.Duration `env:"INFRA__ENRICHMENT__RETRY_BASE_DELAY" env-default:"200ms" validate:"required"`
+	RetryMaxDelay    time.Duration `env:"INFRA__ENRICHMENT__RETRY_MAX_DELAY" env-default:"5s" validate:"required,gtefield=RetryBaseDelay"`
+
+	BreakerFailureThreshold    int           `env:"INFRA__ENRICHMENT__BREAKER_FAILURE_THRESHOLD" env-default:"5" validate:"required,min=1"`
+	BreakerOpenTimeout         time.Duration `env:"INFRA__ENRICHMENT__BREAKER_OPEN_TIMEOUT" env-default:"30s" validate:"required"`
+	BreakerHalfOpenMaxRequests int           `env:"INFRA__ENRICHMENT__BREAKER_HALF_OPEN_MAX_REQUESTS" env-default:"1" validate:"required,min=1"`
+
+	CacheTTL        time.Duration `env:"INFRA__ENRICHMENT__CACHE_TTL" env-default:"720h" validate:"required"`
+	CacheMemorySize int           `env:"INFRA__ENRICHMENT__CACHE_MEMORY_SIZE" env-default:"10000" validate:"required,min=1"`
+
+	// CacheNegativeTTL is how long an answer with no prediction is kept, so
+	// that a name the provider did not know yet is asked about again soon.
+	CacheNegativeTTL time.Duration `env:"INFRA__ENRICHMENT__CACHE_NEGATIVE_TTL" env-default:"1h" validate:"required,ltefield=CacheTTL"`
+
+	DefaultCountryHint string `env:"INFRA__ENRICHMENT__DEFAULT_COUNTRY_HINT" validate:"omitempty,iso3166_1_alpha2"`
+	Transliteration    string `env:"INFRA__ENRICHMENT__TRANSLITERATION" env-default:"gost" validate:"required,oneof=none gost iso9"`
+	SexRulesMode       string `env:"INFRA__ENRICHMENT__SEX_RULES_MODE" env-default:"fallback" validate:"required,oneof=disabled fallback primary"`
+
+	// Provider chains are tried left to right. A cache entry stores what the
+	// providers after it return.
+	AgeProviders         []string `env:"INFRA__ENRICHMENT__AGE_PROVIDERS" env-default:"cache,remote" env-separator:"," validate:"required,min=1,unique,dive,oneof=remote cache dataset"`
+	SexProviders         []string `env:"INFRA__ENRICHMENT__SEX_PROVIDERS" env-default:"cache,remote" env-separator:"," validate:"required,min=1,unique,dive,oneof=remote cache dataset"`
+	NationalityProviders []string `env:"INFRA__ENRICHMENT__NATIONALITY_PROVIDERS" env-default:"cache,remote" env-separator:"," validate:"required,min=1,unique,dive,oneof=remote cache dataset"`
+	DatasetPath          string   `env:"INFRA__ENRICHMENT__DATASET_PATH"`
+
+	// In async mode users are stored as pending and enriched by the worker
+	// pool, which also retries failed attempts with a doubling delay. Async
+	// mode without workers would leave every new user pending for good.
+	Async              bool          `env:"INFRA__ENRICHMENT__ASYNC" env-default:"false"`
+	WorkerCount        int           `env:"INFRA__ENRICHMENT__WORKER_COUNT" env-default:"4" validate:"required_if=Async true,min=0,max=64"`
+	WorkerPollInterval time.Duration `env:"INFRA__ENRICHMENT__WORKER_POLL_INTERVAL" env-default:"1s" validate:"required"`
+	WorkerMaxAttempts  int           `env:"INFRA__ENRICHMENT__WORKER_MAX_ATTEMPTS" env-default:"5" validate:"required,min=1,max=20"`
+	WorkerRetryDelay   time.Duration `env:"INFRA__ENRICHMENT__WORKER_RETRY_DELAY" env-default:"30s" validate:"required"`
+	// WorkerBatchSize is how many users a worker claims at once. Their names
+	// are looked up together through the providers' batch endpoints, which
+	// take up to 10 names per request.
+	WorkerBatchSize int `env:"INFRA__ENRICHMENT__WORKER_BATCH_SIZE" env-default:"10" validate:"required,min=1,max=100"`
+	// WorkerLeaseTimeout is how long a claimed user is left to its worker.
+	// It has to outlast the enrichment of a whole batch, retries included; a
+	// user whose lease runs out is taken over by another worker.
+	WorkerLeaseTimeout time.Duration `env:"INFRA__ENRICHMENT__WORKER_LEASE_TIMEOUT" env-default:"5m" validate:"required"`
+
+	// Bulk re-enrichment queues users this many at a time, each batch in its
+	// own transaction.
+	RequeueBatchSize int `env:"INFRA__ENRICHMENT__REQUEUE_BATCH_SIZE" env-default:"1000" validate:"required,min=1,max=100000"`
+}
+
 func New() (*Config, error) {
 	cfg := &Config{}
 	if err := cleanenv.ReadEnv(cfg); err != nil {
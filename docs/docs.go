@@ -19,9 +19,121 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/diagnostics/enrichment": {
+            "get": {
+                "description": "Возвращает состояние circuit breaker и счетчики запросов для каждого внешнего провайдера",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Диагностика"
+                ],
+                "summary": "Состояние провайдеров обогащения",
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-array_github_com_FlyKarlik_effectiveMobile_internal_domain_ProviderState"
+                        }
+                    }
+                }
+            }
+        },
+        "/enrichment/cache": {
+            "delete": {
+                "description": "Удаляет закэшированные результаты обогащения (все, по имени или только просроченные)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Обогащение"
+                ],
+                "summary": "Очистка кэша обогащения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя, для которого нужно удалить записи",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Удалять только просроченные записи",
+                        "name": "expired_only",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная очистка",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_PurgeEnrichmentCacheResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/enrichment/cache/warm": {
+            "post": {
+                "description": "Запрашивает возраст, пол и национальность для списка имен пакетными запросами (до 10 имен за запрос) и сохраняет результаты в кэш",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Обогащение"
+                ],
+                "summary": "Прогрев кэша обогащения",
+                "parameters": [
+                    {
+                        "description": "Имена для прогрева",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.WarmEnrichmentCacheInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный прогрев",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_WarmEnrichmentCacheResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Невалидные данные запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Ошибки валидации полей",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    }
+                }
+            }
+        },
         "/users": {
             "get": {
-                "description": "Возвращает список пользователей с пагинацией и фильтрацией",
+                "description": "Возвращает список пользователей с пагинацией и фильтрацией\nЕсли есть следующая страница, в ответе приходит next_cursor — его нужно передать в cursor вместе с той же сортировкой.",
                 "consumes": [
                     "application/json"
                 ],
@@ -46,10 +158,39 @@ const docTemplate = `{
                         "minimum": 0,
                         "type": "integer",
                         "default": 0,
-                        "description": "Смещение (по умолчанию 0)",
+                        "description": "Смещение (по умолчанию 0), игнорируется при передаче cursor",
                         "name": "offset",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "estimate",
+                            "none"
+                        ],
+                        "type": "string",
+                        "description": "Подсчет total: exact — точно, estimate — по оценке планировщика (в ответе total_estimated), none — без подсчета. По умолчанию exact, а при переданном cursor — none",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Сортировка: поля через запятую, минус перед полем — по убыванию (например, surname,-created_at). Доступны id, name, surname, patronymic, nationality, sex, age, sex_probability, nationality_probability, enrichment_status, created_at, updated_at, а при поиске по q — score. При поиске по q без сортировки результаты упорядочены по убыванию score",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Нечеткий поиск по ФИО с учетом опечаток (например, Ivanof Dmitry)",
+                        "name": "q",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Фильтр по имени (частичное совпадение)",
@@ -70,7 +211,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Фильтр по национальности (точное совпадение)",
+                        "description": "Фильтр по национальности, несколько значений через запятую (например, RU,KZ)",
                         "name": "nationality",
                         "in": "query"
                     },
@@ -91,6 +232,100 @@ const docTemplate = `{
                         "description": "Фильтр по возрасту (точное совпадение)",
                         "name": "age",
                         "in": "query"
+                    },
+                    {
+                        "maximum": 120,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Минимальный возраст включительно",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 120,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Максимальный возраст включительно",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true — только с известным возрастом, false — только без возраста",
+                        "name": "has_age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан не раньше (RFC 3339 или YYYY-MM-DD, включительно)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан раньше (RFC 3339 или YYYY-MM-DD, не включительно)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Обновлен не раньше (RFC 3339 или YYYY-MM-DD)",
+                        "name": "updated_since",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1,
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Минимальная уверенность в определении пола",
+                        "name": "min_sex_probability",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1,
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Минимальная уверенность в определении национальности",
+                        "name": "min_nationality_probability",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "PROVIDER",
+                            "RULES",
+                            "MANUAL"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по источнику значения пола",
+                        "name": "sex_source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по стране среди всех кандидатов национальности",
+                        "name": "nationality_candidate",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "processing",
+                            "done",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по статусу обогащения",
+                        "name": "enrichment_status",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "nationality_candidates"
+                        ],
+                        "type": "string",
+                        "description": "Дополнительные данные в ответе",
+                        "name": "include",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -103,19 +338,19 @@ const docTemplate = `{
                     "400": {
                         "description": "Невалидные параметры запроса",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Создает нового пользователя с указанными данными",
+                "description": "Создает нового пользователя с указанными данными.\nВ асинхронном режиме пользователь сохраняется со статусом pending и обогащается в фоне, ответ — 202.",
                 "consumes": [
                     "application/json"
                 ],
@@ -138,28 +373,341 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
+                    "201": {
                         "description": "Успешное создание пользователя",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_User"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Путь к созданному пользователю"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "Пользователь создан, обогащение выполняется в фоне",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_User"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Путь к созданному пользователю"
+                            }
                         }
                     },
                     "400": {
                         "description": "Невалидные данные запроса",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Ошибки валидации полей или нарушение ограничений",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/enrich": {
+            "post": {
+                "description": "Ставит пользователей, подходящих под фильтр, в очередь фонового обогащения и возвращает задачу.\nБез force в очередь попадают только пользователи с незаполненными полями.\nНужен хотя бы один фильтр; чтобы обогатить всех пользователей, передайте all=true.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Повторное обогащение пользователей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Нечеткий поиск по ФИО с учетом опечаток (например, Ivanof Dmitry)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по имени (частичное совпадение)",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по фамилии (частичное совпадение)",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по отчеству (частичное совпадение)",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по национальности, несколько значений через запятую (например, RU,KZ)",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "MALE",
+                            "FEMALE"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по полу",
+                        "name": "sex",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 120,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Фильтр по возрасту (точное совпадение)",
+                        "name": "age",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 120,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Минимальный возраст включительно",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 120,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Максимальный возраст включительно",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true — только с известным возрастом, false — только без возраста",
+                        "name": "has_age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан не раньше (RFC 3339 или YYYY-MM-DD, включительно)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан раньше (RFC 3339 или YYYY-MM-DD, не включительно)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Обновлен не раньше (RFC 3339 или YYYY-MM-DD)",
+                        "name": "updated_since",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1,
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Минимальная уверенность в определении пола",
+                        "name": "min_sex_probability",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1,
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Минимальная уверенность в определении национальности",
+                        "name": "min_nationality_probability",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "PROVIDER",
+                            "RULES",
+                            "MANUAL"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по источнику значения пола",
+                        "name": "sex_source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по стране среди всех кандидатов национальности",
+                        "name": "nationality_candidate",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "processing",
+                            "done",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по статусу обогащения",
+                        "name": "enrichment_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Перезаписать уже заполненные поля",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Подтверждает обогащение всех пользователей, когда фильтры не заданы",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Задача создана",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_EnrichmentJob"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Путь к статусу задачи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Невалидные фильтры или не задан ни один фильтр без all=true",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/enrich/jobs/{id}": {
+            "get": {
+                "description": "Возвращает число пользователей задачи в каждом статусе обогащения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Статус задачи обогащения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_EnrichmentJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Невалидные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     }
                 }
             }
         },
         "/users/{id}": {
+            "get": {
+                "description": "Возвращает пользователя по его идентификатору",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Получение пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "nationality_candidates"
+                        ],
+                        "type": "string",
+                        "description": "Дополнительные данные в ответе",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_User"
+                        }
+                    },
+                    "400": {
+                        "description": "Невалидные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    }
+                }
+            },
             "delete": {
                 "description": "Удаляет пользователя по его идентификатору",
                 "consumes": [
@@ -171,7 +719,55 @@ const docTemplate = `{
                 "tags": [
                     "Пользователи"
                 ],
-                "summary": "Удаление пользователя",
+                "summary": "Удаление пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное удаление",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Невалидные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Обновляет данные пользователя по его идентификатору",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Обновление пользователя",
                 "parameters": [
                     {
                         "type": "string",
@@ -179,47 +775,61 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Данные для обновления",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.UpdateUserInput"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное удаление",
+                        "description": "Успешное обновление",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_User"
                         }
                     },
                     "400": {
                         "description": "Невалидные параметры запроса",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     },
                     "404": {
                         "description": "Пользователь не найден",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Ошибки валидации полей или нарушение ограничений",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Обновляет данные пользователя по его идентификатору",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/users/{id}/enrich": {
+            "post": {
+                "description": "Заново запрашивает возраст, пол и национальность пользователя.\nБез force заполняются только отсутствующие поля, с force найденные значения заменяют сохраненные.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Пользователи"
                 ],
-                "summary": "Обновление пользователя",
+                "summary": "Повторное обогащение пользователя",
                 "parameters": [
                     {
                         "type": "string",
@@ -229,38 +839,41 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Данные для обновления",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.UpdateUserInput"
-                        }
+                        "type": "boolean",
+                        "description": "Перезаписать уже заполненные поля",
+                        "name": "force",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное обновление",
+                        "description": "Пользователь после обогащения",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_User"
                         }
                     },
                     "400": {
                         "description": "Невалидные параметры запроса",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     },
                     "404": {
                         "description": "Пользователь не найден",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже обогащается",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-any"
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails"
                         }
                     }
                 }
@@ -275,12 +888,123 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "data": {},
-                "error": {},
                 "status": {
                     "type": "boolean"
                 }
             }
         },
+        "github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-array_github_com_FlyKarlik_effectiveMobile_internal_domain_ProviderState": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.ProviderState"
+                    }
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_EnrichmentJob": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.EnrichmentJob"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_PurgeEnrichmentCacheResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.PurgeEnrichmentCacheResult"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_User": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.User"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.BaseResponse-github_com_FlyKarlik_effectiveMobile_internal_domain_WarmEnrichmentCacheResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.WarmEnrichmentCacheResult"
+                },
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_delivery_http_response.ProblemDetails": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_errs.ErrorCodeEnum"
+                        }
+                    ],
+                    "example": 1
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "user not found"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/v1/users/6a1f5c1e-0a8b-4d6e-9c43-6f1b2f2b7f10"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "urn:users-api:problem:user-not-found"
+                },
+                "violations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_errs.Violation"
+                    }
+                }
+            }
+        },
         "github_com_FlyKarlik_effectiveMobile_internal_domain.CreateUserInput": {
             "type": "object",
             "required": [
@@ -288,14 +1012,112 @@ const docTemplate = `{
                 "surname"
             ],
             "properties": {
-                "name": {
+                "country_hint": {
                     "type": "string"
                 },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
                 "patronymic": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100
                 },
                 "surname": {
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.EnrichmentJob": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "force": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.EnrichmentStatusEnum": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "processing",
+                "done",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "PendingEnrichmentStatus",
+                "ProcessingEnrichmentStatus",
+                "DoneEnrichmentStatus",
+                "FailedEnrichmentStatus"
+            ]
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.NationalityCandidate": {
+            "type": "object",
+            "properties": {
+                "country_id": {
+                    "type": "string"
+                },
+                "probability": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.ProviderState": {
+            "type": "object",
+            "properties": {
+                "breaker_state": {
+                    "type": "string"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "opened_at": {
                     "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "rate_limited": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "retries": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.PurgeEnrichmentCacheResult": {
+            "type": "object",
+            "properties": {
+                "purged": {
+                    "type": "integer"
                 }
             }
         },
@@ -310,26 +1132,54 @@ const docTemplate = `{
                 "MaleSexEnum"
             ]
         },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.SexSourceEnum": {
+            "type": "string",
+            "enum": [
+                "PROVIDER",
+                "RULES",
+                "MANUAL"
+            ],
+            "x-enum-varnames": [
+                "ProviderSexSource",
+                "RulesSexSource",
+                "ManualSexSource"
+            ]
+        },
         "github_com_FlyKarlik_effectiveMobile_internal_domain.UpdateUserInput": {
             "type": "object",
             "properties": {
                 "age": {
-                    "type": "integer"
+                    "type": "integer",
+                    "maximum": 119,
+                    "minimum": 1
                 },
                 "name": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1
                 },
                 "nationality": {
                     "type": "string"
                 },
                 "patronymic": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100
                 },
                 "sex": {
-                    "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.SexEnum"
+                    "enum": [
+                        "MALE",
+                        "FEMALE"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.SexEnum"
+                        }
+                    ]
                 },
                 "surname": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1
                 }
             }
         },
@@ -342,6 +1192,21 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "enrichment_attempts": {
+                    "type": "integer"
+                },
+                "enrichment_error": {
+                    "type": "string"
+                },
+                "enrichment_job_id": {
+                    "type": "string"
+                },
+                "enrichment_sample_count": {
+                    "type": "integer"
+                },
+                "enrichment_status": {
+                    "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.EnrichmentStatusEnum"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -351,12 +1216,31 @@ const docTemplate = `{
                 "nationality": {
                     "type": "string"
                 },
+                "nationality_candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.NationalityCandidate"
+                    }
+                },
+                "nationality_probability": {
+                    "type": "number"
+                },
                 "patronymic": {
                     "type": "string"
                 },
+                "score": {
+                    "description": "Score is the full-name search relevance and is only set for results\nof a search by q.",
+                    "type": "number"
+                },
                 "sex": {
                     "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.SexEnum"
                 },
+                "sex_probability": {
+                    "type": "number"
+                },
+                "sex_source": {
+                    "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.SexSourceEnum"
+                },
                 "surname": {
                     "type": "string"
                 },
@@ -365,6 +1249,85 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.WarmEnrichmentCacheInput": {
+            "type": "object",
+            "required": [
+                "names"
+            ],
+            "properties": {
+                "country_hint": {
+                    "type": "string"
+                },
+                "names": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_domain.WarmEnrichmentCacheResult": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "warmed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_errs.ErrorCodeEnum": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3,
+                4,
+                5,
+                6,
+                7,
+                8,
+                9,
+                10,
+                11,
+                12,
+                13
+            ],
+            "x-enum-varnames": [
+                "CodeUnknown",
+                "CodeUserNotFound",
+                "CodeInvalidRequest",
+                "CodeParamRequired",
+                "CodeInvalidUserID",
+                "CodeConflict",
+                "CodeUnprocessable",
+                "CodeValidationFailed",
+                "CodeCacheEntryNotFound",
+                "CodeEnrichmentJobNotFound",
+                "CodeInvalidJobID",
+                "CodeInvalidSort",
+                "CodeInvalidCursor",
+                "CodeEnrichmentInProgress"
+            ]
+        },
+        "github_com_FlyKarlik_effectiveMobile_internal_errs.Violation": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
         "github_com_FlyKarlik_effectiveMobile_pkg_generics.ItemsOutput-github_com_FlyKarlik_effectiveMobile_internal_domain_User": {
             "type": "object",
             "properties": {
@@ -375,11 +1338,17 @@ const docTemplate = `{
                         "$ref": "#/definitions/github_com_FlyKarlik_effectiveMobile_internal_domain.User"
                     }
                 },
+                "next_cursor": {
+                    "type": "string"
+                },
                 "success": {
                     "type": "boolean"
                 },
                 "total": {
                     "type": "integer"
+                },
+                "total_estimated": {
+                    "type": "boolean"
                 }
             }
         }
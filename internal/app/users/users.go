@@ -6,16 +6,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/FlyKarlik/effectiveMobile/config"
 	http_handler "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/handler"
 	http_middleware "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/middleware"
 	http_router "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/router"
 	http_server "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/server"
+	enrichment_worker "github.com/FlyKarlik/effectiveMobile/internal/delivery/worker/enrichment"
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
 	"github.com/FlyKarlik/effectiveMobile/internal/driver"
 	"github.com/FlyKarlik/effectiveMobile/internal/repository"
 	"github.com/FlyKarlik/effectiveMobile/internal/usecase"
+	"github.com/FlyKarlik/effectiveMobile/pkg/cursor"
 	"github.com/FlyKarlik/effectiveMobile/pkg/database/postgres"
+	"github.com/FlyKarlik/effectiveMobile/pkg/httpclient"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
 )
 
@@ -52,31 +57,20 @@ func (a *AppUsers) Start() error {
 		a.logger.Info(layer, method, "Database connection closed")
 	}()
 
-	a.logger.Info(layer, method, "Initializing repository")
-	repo, err := repository.New(
-		repository.WithUserRepo(a.logger, dbConn),
-	)
-	if err != nil {
-		a.logger.Error(layer, method, "Failed to initialize repository", err)
-		return err
-	}
+	httpClient := httpclient.New(&a.cfg.Infra.HTTPClient)
+	defer httpClient.CloseIdleConnections()
 
-	driver, err := driver.New(
-		driver.WithUserDriver(a.logger),
-	)
+	usecase, err := a.initUsecase(dbConn, httpClient)
 	if err != nil {
-		a.logger.Error(layer, method, "Failed to initialize driver", err)
 		return err
 	}
 
-	a.logger.Info(layer, method, "Initializing usecase")
-	usecase, err := usecase.New(
-		usecase.WithUserUsecase(a.logger, repo.IUserRepository, driver.IUserDriver),
-	)
-	if err != nil {
-		a.logger.Error(layer, method, "Failed to initialize usecase", err)
-		return err
-	}
+	a.logger.Info(layer, method, "Starting enrichment workers")
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+
+	enrichmentWorker := enrichment_worker.New(a.logger, usecase, &a.cfg.Infra.Enrichment)
+	enrichmentWorker.Start(workerCtx)
 
 	a.logger.Info(layer, method, "Initializing HTTP components")
 	httpHandler := http_handler.New(a.logger, usecase)
@@ -105,9 +99,143 @@ func (a *AppUsers) Start() error {
 	}
 	a.logger.Info(layer, method, "HTTP server shutdown completed")
 
+	a.logger.Info(layer, method, "Stopping enrichment workers")
+	stopWorkers()
+	enrichmentWorker.Wait()
+	a.logger.Info(layer, method, "Enrichment workers stopped")
+
 	return nil
 }
 
+// Enrich queues the users matching the filter for re-enrichment. Unless
+// detach is set it also runs the worker pool in this process and returns once
+// none of the job's users is pending any more.
+func (a *AppUsers) Enrich(input domain.EnrichUsersInput, detach bool) error {
+	const layer = "users"
+	const method = "Enrich"
+
+	if !detach && a.cfg.Infra.Enrichment.WorkerCount == 0 {
+		err := errors.New("no enrichment workers configured, use -detach to only queue the users")
+		a.logger.Error(layer, method, "Nothing would process the job", err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConn, err := postgres.NewPostgresDB(&a.cfg.Infra.Postgres)
+	if err != nil {
+		a.logger.Error(layer, method, "Failed to connect to database", err)
+		return err
+	}
+	defer dbConn.Close()
+
+	httpClient := httpclient.New(&a.cfg.Infra.HTTPClient)
+	defer httpClient.CloseIdleConnections()
+
+	usecase, err := a.initUsecase(dbConn, httpClient)
+	if err != nil {
+		return err
+	}
+
+	job, err := usecase.EnrichUsers(ctx, input)
+	if err != nil {
+		a.logger.Error(layer, method, "Failed to create enrichment job", err)
+		return err
+	}
+
+	a.logger.Info(layer, method, "Enrichment job created", "job_id", job.ID, "total", job.Total, "force", job.Force)
+	if detach || job.Total == 0 {
+		return nil
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+
+	enrichmentWorker := enrichment_worker.New(a.logger, usecase, &a.cfg.Infra.Enrichment)
+	enrichmentWorker.Start(workerCtx)
+
+	ticker := time.NewTicker(a.cfg.Infra.Enrichment.WorkerPollInterval)
+	defer ticker.Stop()
+
+	for job.Pending > 0 {
+		select {
+		case <-ctx.Done():
+			a.logger.Info(layer, method, "Interrupted, remaining users stay queued", "job_id", job.ID, "pending", job.Pending)
+			stopWorkers()
+			enrichmentWorker.Wait()
+			return nil
+		case <-ticker.C:
+		}
+
+		progress, err := usecase.GetEnrichmentJob(ctx, job.ID)
+		if err != nil {
+			a.logger.Error(layer, method, "Failed to get enrichment job", err, "job_id", job.ID)
+			continue
+		}
+		job = progress
+
+		a.logger.Info(layer, method, "Enrichment job progress",
+			"job_id", job.ID,
+			"pending", job.Pending,
+			"done", job.Done,
+			"failed", job.Failed)
+	}
+
+	stopWorkers()
+	enrichmentWorker.Wait()
+
+	a.logger.Info(layer, method, "Enrichment job finished", "job_id", job.ID, "done", job.Done, "failed", job.Failed)
+	return nil
+}
+
+func (a *AppUsers) initUsecase(dbConn postgres.Querier, httpClient *http.Client) (*usecase.Usecase, error) {
+	const layer = "users"
+	const method = "initUsecase"
+
+	a.logger.Info(layer, method, "Initializing repository")
+	repo, err := repository.New(
+		repository.WithUserRepo(a.logger, dbConn, a.cfg.AppUsers.SearchSimilarityThreshold, a.cfg.Infra.Enrichment.RequeueBatchSize),
+		repository.WithEnrichmentCacheRepo(a.logger, dbConn),
+	)
+	if err != nil {
+		a.logger.Error(layer, method, "Failed to initialize repository", err)
+		return nil, err
+	}
+
+	a.logger.Info(layer, method, "Initializing driver")
+	driver, err := driver.New(
+		driver.WithProviderChains(a.logger, httpClient, repo.IEnrichmentCacheRepository, &a.cfg.Infra.Enrichment),
+		driver.WithSingleflight(a.logger),
+		driver.WithSexRules(a.logger),
+	)
+	if err != nil {
+		a.logger.Error(layer, method, "Failed to initialize driver", err)
+		return nil, err
+	}
+
+	cursorSigner, err := cursor.NewSigner(a.cfg.AppUsers.CursorSecret)
+	if err != nil {
+		a.logger.Error(layer, method, "Failed to initialize cursor signer", err)
+		return nil, err
+	}
+	if a.cfg.AppUsers.CursorSecret == "" {
+		a.logger.Warn(layer, method, "Cursor secret is not set, cursors are valid until restart", nil)
+	}
+
+	a.logger.Info(layer, method, "Initializing usecase")
+	usecase, err := usecase.New(
+		usecase.WithUserUsecase(a.logger, repo.IUserRepository, driver.IUserDriver, driver.SexRules, cursorSigner, &a.cfg.Infra.Enrichment),
+		usecase.WithEnrichmentUsecase(a.logger, driver.IUserDriver, driver.IEnrichmentCache, &a.cfg.Infra.Enrichment),
+	)
+	if err != nil {
+		a.logger.Error(layer, method, "Failed to initialize usecase", err)
+		return nil, err
+	}
+
+	return usecase, nil
+}
+
 func (a *AppUsers) signalHandler(ctx context.Context) {
 	const layer = "users"
 	const method = "signalHandler"
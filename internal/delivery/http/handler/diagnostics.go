@@ -0,0 +1,20 @@
+package http_handler
+
+import (
+	"net/http"
+
+	http_response "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/response"
+	_ "github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Состояние провайдеров обогащения
+// @Description Возвращает состояние circuit breaker и счетчики запросов для каждого внешнего провайдера
+// @Tags Диагностика
+// @Produce json
+// @Success 200 {object} http_response.BaseResponse[[]domain.ProviderState] "Успешный ответ"
+// @Router /diagnostics/enrichment [get]
+func (h *HTTPHandler) GetEnrichmentProviders(c *gin.Context) {
+	states := h.usecase.GetProvidersState(c.Request.Context())
+	http_response.New(c, http.StatusOK, true, states)
+}
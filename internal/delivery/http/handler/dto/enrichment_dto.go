@@ -0,0 +1,24 @@
+package http_dto
+
+import (
+	"strconv"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func GetEnrichmentCachePurgeFilterFromQuery(c *gin.Context) domain.EnrichmentCachePurgeFilter {
+	filter := domain.EnrichmentCachePurgeFilter{}
+
+	if name := c.Query("name"); name != "" {
+		filter.Name = &name
+	}
+
+	if expiredOnlyStr := c.Query("expired_only"); expiredOnlyStr != "" {
+		if expiredOnly, err := strconv.ParseBool(expiredOnlyStr); err == nil {
+			filter.ExpiredOnly = expiredOnly
+		}
+	}
+
+	return filter
+}
@@ -2,8 +2,10 @@ package http_dto
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,7 +16,12 @@ func validateLimit(limit int64) int64 {
 	return limit
 }
 
-func GetPaginationFromQuery(c *gin.Context) domain.Pagination {
+// GetPaginationFromQuery reads limit, offset, cursor and count. Out of range
+// limits and offsets fall back to the defaults, while an unknown count mode is
+// rejected, as falling back to an exact count would cost the very query the
+// client tried to avoid. A client following a cursor has had the total with
+// the first page, so unless asked for, it is not counted again.
+func GetPaginationFromQuery(c *gin.Context) (domain.Pagination, error) {
 	limitStr := c.DefaultQuery("limit", "10")
 	offsetStr := c.DefaultQuery("offset", "0")
 
@@ -29,8 +36,52 @@ func GetPaginationFromQuery(c *gin.Context) domain.Pagination {
 		offset = 0
 	}
 
+	cursor := c.Query("cursor")
+
+	defaultCount := domain.ExactCountMode
+	if cursor != "" {
+		defaultCount = domain.NoneCountMode
+	}
+
+	count := domain.CountModeEnum(c.DefaultQuery("count", string(defaultCount)))
+	switch count {
+	case domain.ExactCountMode, domain.EstimateCountMode, domain.NoneCountMode:
+	default:
+		return domain.Pagination{}, errs.NewInvalidParamError("count", "must be one of exact, estimate, none")
+	}
+
 	return domain.Pagination{
 		Limit:  limit,
 		Offset: offset,
+		Cursor: cursor,
+		Count:  count,
+	}, nil
+}
+
+// GetSortFromQuery parses sort=surname,-created_at: fields in priority order,
+// a leading minus for descending and an optional plus for ascending. Whether
+// a field can be sorted by is checked further down.
+func GetSortFromQuery(c *gin.Context) domain.Sort {
+	var sort domain.Sort
+
+	for _, value := range strings.Split(c.Query("sort"), ",") {
+		value = strings.TrimSpace(value)
+
+		field := domain.SortField{}
+		switch {
+		case strings.HasPrefix(value, "-"):
+			field.Desc = true
+			value = value[1:]
+		case strings.HasPrefix(value, "+"):
+			value = value[1:]
+		}
+
+		if value == "" {
+			continue
+		}
+		field.Field = value
+		sort = append(sort, field)
 	}
+
+	return sort
 }
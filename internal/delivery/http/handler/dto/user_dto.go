@@ -1,15 +1,25 @@
 package http_dto
 
 import (
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
 	"github.com/gin-gonic/gin"
 )
 
-func GetUserFilterFromQuery(c *gin.Context) domain.UserFilter {
+// GetUserFilterFromQuery reads the user filters. A value that cannot be
+// parsed is rejected rather than ignored, so a typo never widens the filter.
+func GetUserFilterFromQuery(c *gin.Context) (domain.UserFilter, error) {
 	filter := domain.UserFilter{}
 
+	if query := strings.TrimSpace(c.Query("q")); query != "" {
+		filter.Query = &query
+	}
+
 	if name := c.Query("name"); name != "" {
 		filter.Name = &name
 	}
@@ -22,20 +32,173 @@ func GetUserFilterFromQuery(c *gin.Context) domain.UserFilter {
 		filter.Patronymic = &patronymic
 	}
 
-	if ageStr := c.Query("age"); ageStr != "" {
-		if age, err := strconv.Atoi(ageStr); err == nil {
-			int64Age := int64(age)
-			filter.Age = &int64Age
+	var err error
+	if filter.Age, err = parseIntQuery(c, "age"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if filter.AgeMin, err = parseIntQuery(c, "age_min"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if filter.AgeMax, err = parseIntQuery(c, "age_max"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if c.Query("has_age") != "" {
+		hasAge, err := parseBoolQuery(c, "has_age")
+		if err != nil {
+			return domain.UserFilter{}, err
+		}
+		filter.HasAge = &hasAge
+	}
+
+	// Both nationality=RU,KZ and nationality=RU&nationality=KZ are accepted.
+	for _, value := range c.QueryArray("nationality") {
+		for _, nationality := range strings.Split(value, ",") {
+			if nationality = strings.ToUpper(strings.TrimSpace(nationality)); nationality != "" {
+				filter.Nationalities = append(filter.Nationalities, nationality)
+			}
+		}
+	}
+
+	if filter.CreatedFrom, err = parseTimeQuery(c, "created_from"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if filter.CreatedTo, err = parseTimeQuery(c, "created_to"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if filter.UpdatedSince, err = parseTimeQuery(c, "updated_since"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	filter.Sex, err = parseEnumQuery(c, "sex", domain.MaleSexEnum, domain.FemaleSexEnum)
+	if err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if filter.MinSexProbability, err = parseFloatQuery(c, "min_sex_probability"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if filter.MinNationalityProbability, err = parseFloatQuery(c, "min_nationality_probability"); err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	filter.SexSource, err = parseEnumQuery(c, "sex_source",
+		domain.ProviderSexSource, domain.RulesSexSource, domain.ManualSexSource)
+	if err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	filter.EnrichmentStatus, err = parseEnumQuery(c, "enrichment_status",
+		domain.PendingEnrichmentStatus, domain.ProcessingEnrichmentStatus,
+		domain.DoneEnrichmentStatus, domain.FailedEnrichmentStatus)
+	if err != nil {
+		return domain.UserFilter{}, err
+	}
+
+	if candidate := c.Query("nationality_candidate"); candidate != "" {
+		filter.NationalityCandidate = &candidate
+	}
+
+	return filter, nil
+}
+
+func parseIntQuery(c *gin.Context, key string) (*int64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, errs.NewInvalidParamError(key, "must be an integer")
+	}
+	return &parsed, nil
+}
+
+func parseFloatQuery(c *gin.Context, key string) (*float64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, errs.NewInvalidParamError(key, "must be a number")
+	}
+	return &parsed, nil
+}
+
+// parseEnumQuery accepts one of values only, so that a misspelled value is
+// reported instead of matching nobody.
+func parseEnumQuery[T ~string](c *gin.Context, key string, values ...T) (*T, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed := T(value)
+	if !slices.Contains(values, parsed) {
+		allowed := make([]string, 0, len(values))
+		for _, v := range values {
+			allowed = append(allowed, string(v))
 		}
+		return nil, errs.NewInvalidParamError(key, "must be one of "+strings.Join(allowed, ", "))
 	}
+	return &parsed, nil
+}
 
-	if nationality := c.Query("nationality"); nationality != "" {
-		filter.Nationality = &nationality
+func parseBoolQuery(c *gin.Context, key string) (bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return false, nil
 	}
 
-	if sex := c.Query("sex"); sex != "" {
-		filter.Sex = (*domain.SexEnum)(&sex)
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errs.NewInvalidParamError(key, "must be true or false")
 	}
+	return parsed, nil
+}
+
+// parseTimeQuery accepts an RFC 3339 timestamp or a bare date, which is read
+// as midnight UTC.
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return &t, nil
+	}
+	return nil, errs.NewInvalidParamError(key, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+func GetUserIncludeFromQuery(c *gin.Context) domain.UserInclude {
+	include := domain.UserInclude{}
+
+	for _, value := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(value) {
+		case "nationality_candidates":
+			include.NationalityCandidates = true
+		}
+	}
+
+	return include
+}
+
+func GetForceFromQuery(c *gin.Context) (bool, error) {
+	return parseBoolQuery(c, "force")
+}
 
-	return filter
+func GetAllFromQuery(c *gin.Context) (bool, error) {
+	return parseBoolQuery(c, "all")
 }
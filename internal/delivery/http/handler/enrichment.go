@@ -0,0 +1,64 @@
+package http_handler
+
+import (
+	"net/http"
+
+	http_dto "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/handler/dto"
+	http_response "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/response"
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Очистка кэша обогащения
+// @Description Удаляет закэшированные результаты обогащения (все, по имени или только просроченные)
+// @Tags Обогащение
+// @Produce json
+// @Param name query string false "Имя, для которого нужно удалить записи"
+// @Param expired_only query bool false "Удалять только просроченные записи"
+// @Success 200 {object} http_response.BaseResponse[domain.PurgeEnrichmentCacheResult] "Успешная очистка"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
+// @Router /enrichment/cache [delete]
+func (h *HTTPHandler) PurgeEnrichmentCache(c *gin.Context) {
+	filter := http_dto.GetEnrichmentCachePurgeFilterFromQuery(c)
+
+	result, err := h.usecase.PurgeCache(c.Request.Context(), filter)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	http_response.New(c, http.StatusOK, true, result)
+}
+
+// @Summary Прогрев кэша обогащения
+// @Description Запрашивает возраст, пол и национальность для списка имен пакетными запросами (до 10 имен за запрос) и сохраняет результаты в кэш
+// @Tags Обогащение
+// @Accept json
+// @Produce json
+// @Param input body domain.WarmEnrichmentCacheInput true "Имена для прогрева"
+// @Success 200 {object} http_response.BaseResponse[domain.WarmEnrichmentCacheResult] "Успешный прогрев"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные данные запроса"
+// @Failure 422 {object} http_response.ProblemDetails "Ошибки валидации полей"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
+// @Router /enrichment/cache/warm [post]
+func (h *HTTPHandler) WarmEnrichmentCache(c *gin.Context) {
+	var input domain.WarmEnrichmentCacheInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		http_response.Error(c, errs.ErrInvalidRequest)
+		return
+	}
+
+	if err := validateInput(input); err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	result, err := h.usecase.WarmCache(c.Request.Context(), input)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	http_response.New(c, http.StatusOK, true, result)
+}
@@ -19,5 +19,5 @@ func (h *HTTPHandler) Ping(c *gin.Context) {
 		DateTime: time.Now().Format(time.RFC1123),
 	}
 
-	http_response.New(c, http.StatusOK, true, resp, nil)
+	http_response.New(c, http.StatusOK, true, resp)
 }
@@ -1,6 +1,7 @@
 package http_handler
 
 import (
+	"fmt"
 	"net/http"
 
 	http_dto "github.com/FlyKarlik/effectiveMobile/internal/delivery/http/handler/dto"
@@ -14,57 +15,132 @@ import (
 
 // @Summary Поиск пользователей
 // @Description Возвращает список пользователей с пагинацией и фильтрацией
+// @Description Если есть следующая страница, в ответе приходит next_cursor — его нужно передать в cursor вместе с той же сортировкой.
 // @Tags Пользователи
 // @Accept json
 // @Produce json
 // @Param limit query int false "Лимит записей (по умолчанию 10)" default(10) minimum(1) maximum(100)
-// @Param offset query int false "Смещение (по умолчанию 0)" default(0) minimum(0)
+// @Param offset query int false "Смещение (по умолчанию 0), игнорируется при передаче cursor" default(0) minimum(0)
+// @Param cursor query string false "Курсор следующей страницы из next_cursor предыдущего ответа"
+// @Param count query string false "Подсчет total: exact — точно, estimate — по оценке планировщика (в ответе total_estimated), none — без подсчета. По умолчанию exact, а при переданном cursor — none" Enums(exact, estimate, none)
+// @Param sort query string false "Сортировка: поля через запятую, минус перед полем — по убыванию (например, surname,-created_at). Доступны id, name, surname, patronymic, nationality, sex, age, sex_probability, nationality_probability, enrichment_status, created_at, updated_at, а при поиске по q — score. При поиске по q без сортировки результаты упорядочены по убыванию score"
+// @Param q query string false "Нечеткий поиск по ФИО с учетом опечаток (например, Ivanof Dmitry)"
 // @Param name query string false "Фильтр по имени (частичное совпадение)"
 // @Param surname query string false "Фильтр по фамилии (частичное совпадение)"
 // @Param patronymic query string false "Фильтр по отчеству (частичное совпадение)"
-// @Param nationality query string false "Фильтр по национальности (точное совпадение)"
+// @Param nationality query string false "Фильтр по национальности, несколько значений через запятую (например, RU,KZ)"
 // @Param sex query string false "Фильтр по полу" Enums(MALE, FEMALE)
 // @Param age query int false "Фильтр по возрасту (точное совпадение)" minimum(1) maximum(120)
+// @Param age_min query int false "Минимальный возраст включительно" minimum(1) maximum(120)
+// @Param age_max query int false "Максимальный возраст включительно" minimum(1) maximum(120)
+// @Param has_age query bool false "true — только с известным возрастом, false — только без возраста"
+// @Param created_from query string false "Создан не раньше (RFC 3339 или YYYY-MM-DD, включительно)"
+// @Param created_to query string false "Создан раньше (RFC 3339 или YYYY-MM-DD, не включительно)"
+// @Param updated_since query string false "Обновлен не раньше (RFC 3339 или YYYY-MM-DD)"
+// @Param min_sex_probability query number false "Минимальная уверенность в определении пола" minimum(0) maximum(1)
+// @Param min_nationality_probability query number false "Минимальная уверенность в определении национальности" minimum(0) maximum(1)
+// @Param sex_source query string false "Фильтр по источнику значения пола" Enums(PROVIDER, RULES, MANUAL)
+// @Param nationality_candidate query string false "Фильтр по стране среди всех кандидатов национальности"
+// @Param enrichment_status query string false "Фильтр по статусу обогащения" Enums(pending, processing, done, failed)
+// @Param include query string false "Дополнительные данные в ответе" Enums(nationality_candidates)
 // @Success 200 {object} generics.ItemsOutput[domain.User] "Успешный ответ"
-// @Failure 400 {object} http_response.BaseResponse[any] "Невалидные параметры запроса"
-// @Failure 500 {object} http_response.BaseResponse[any] "Внутренняя ошибка сервера"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные параметры запроса"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
 // @Router /users [get]
 func (h *HTTPHandler) SearchUsers(c *gin.Context) {
-	pagination := http_dto.GetPaginationFromQuery(c)
-	filter := http_dto.GetUserFilterFromQuery(c)
+	sort := http_dto.GetSortFromQuery(c)
+	include := http_dto.GetUserIncludeFromQuery(c)
 
-	data := h.usecase.SearchUsers(c.Request.Context(), pagination, filter)
+	pagination, err := http_dto.GetPaginationFromQuery(c)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	filter, err := http_dto.GetUserFilterFromQuery(c)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	data := h.usecase.SearchUsers(c.Request.Context(), pagination, sort, filter, include)
 	if !data.Success {
-		http_response.New[any](c, http.StatusInternalServerError, data.Success, nil, errs.ErrUnknown)
+		http_response.Error(c, data.Error)
+		return
+	}
+
+	http_response.New(c, http.StatusOK, true, data)
+}
+
+// @Summary Получение пользователя
+// @Description Возвращает пользователя по его идентификатору
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param id path string true "UUID пользователя"
+// @Param include query string false "Дополнительные данные в ответе" Enums(nationality_candidates)
+// @Success 200 {object} http_response.BaseResponse[domain.User] "Успешный ответ"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные параметры запроса"
+// @Failure 404 {object} http_response.ProblemDetails "Пользователь не найден"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
+// @Router /users/{id} [get]
+func (h *HTTPHandler) GetUserByID(c *gin.Context) {
+	id, err := parseUserID(c)
+	if err != nil {
+		http_response.Error(c, err)
 		return
 	}
 
-	http_response.New(c, http.StatusOK, true, data, nil)
+	include := http_dto.GetUserIncludeFromQuery(c)
+
+	user, err := h.usecase.GetUserByID(c.Request.Context(), id, include)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	http_response.New(c, http.StatusOK, true, user)
 }
 
 // @Summary Создание пользователя
-// @Description Создает нового пользователя с указанными данными
+// @Description Создает нового пользователя с указанными данными.
+// @Description В асинхронном режиме пользователь сохраняется со статусом pending и обогащается в фоне, ответ — 202.
 // @Tags Пользователи
 // @Accept json
 // @Produce json
 // @Param input body domain.CreateUserInput true "Данные для создания пользователя"
-// @Success 200 {object} http_response.BaseResponse[any] "Успешное создание пользователя"
-// @Failure 400 {object} http_response.BaseResponse[any] "Невалидные данные запроса"
-// @Failure 500 {object} http_response.BaseResponse[any] "Внутренняя ошибка сервера"
+// @Success 201 {object} http_response.BaseResponse[domain.User] "Успешное создание пользователя"
+// @Header 201 {string} Location "Путь к созданному пользователю"
+// @Success 202 {object} http_response.BaseResponse[domain.User] "Пользователь создан, обогащение выполняется в фоне"
+// @Header 202 {string} Location "Путь к созданному пользователю"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные данные запроса"
+// @Failure 422 {object} http_response.ProblemDetails "Ошибки валидации полей или нарушение ограничений"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
 // @Router /users [post]
 func (h *HTTPHandler) CreateUser(c *gin.Context) {
 	var input domain.CreateUserInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		http_response.New[any](c, http.StatusBadRequest, false, nil, errs.ErrInvalidRequest)
+		http_response.Error(c, errs.ErrInvalidRequest)
+		return
+	}
+
+	if err := validateInput(input); err != nil {
+		http_response.Error(c, err)
 		return
 	}
 
-	if err := h.usecase.CreateUser(c.Request.Context(), input); err != nil {
-		http_response.New[any](c, http.StatusInternalServerError, false, nil, errs.ErrUnknown)
+	user, err := h.usecase.CreateUser(c.Request.Context(), input)
+	if err != nil {
+		http_response.Error(c, err)
 		return
 	}
 
-	http_response.New[any](c, http.StatusOK, true, nil, nil)
+	c.Header("Location", fmt.Sprintf("/v1/users/%s", user.ID))
+	if user.EnrichmentStatus == domain.PendingEnrichmentStatus {
+		http_response.New(c, http.StatusAccepted, true, user)
+		return
+	}
+	http_response.New(c, http.StatusCreated, true, user)
 }
 
 // @Summary Обновление пользователя
@@ -74,30 +150,37 @@ func (h *HTTPHandler) CreateUser(c *gin.Context) {
 // @Produce json
 // @Param id path string true "UUID пользователя"
 // @Param input body domain.UpdateUserInput true "Данные для обновления"
-// @Success 200 {object} http_response.BaseResponse[any] "Успешное обновление"
-// @Failure 400 {object} http_response.BaseResponse[any] "Невалидные параметры запроса"
-// @Failure 404 {object} http_response.BaseResponse[any] "Пользователь не найден"
-// @Failure 500 {object} http_response.BaseResponse[any] "Внутренняя ошибка сервера"
+// @Success 200 {object} http_response.BaseResponse[domain.User] "Успешное обновление"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные параметры запроса"
+// @Failure 404 {object} http_response.ProblemDetails "Пользователь не найден"
+// @Failure 422 {object} http_response.ProblemDetails "Ошибки валидации полей или нарушение ограничений"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
 // @Router /users/{id} [patch]
 func (h *HTTPHandler) UpdateUser(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		http_response.New[any](c, http.StatusBadRequest, false, nil, errs.ErrParamRequired)
+	id, err := parseUserID(c)
+	if err != nil {
+		http_response.Error(c, err)
 		return
 	}
 
 	var input domain.UpdateUserInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		http_response.New[any](c, http.StatusBadRequest, false, nil, errs.ErrInvalidRequest)
+		http_response.Error(c, errs.ErrInvalidRequest)
 		return
 	}
 
-	if err := h.usecase.UpdateUserByID(c.Request.Context(), uuid.MustParse(id), input); err != nil {
-		http_response.New[any](c, http.StatusInternalServerError, false, nil, errs.ErrUnknown)
+	if err := validateInput(input); err != nil {
+		http_response.Error(c, err)
 		return
 	}
 
-	http_response.New[any](c, http.StatusOK, true, nil, nil)
+	user, err := h.usecase.UpdateUserByID(c.Request.Context(), id, input)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	http_response.New(c, http.StatusOK, true, user)
 }
 
 // @Summary Удаление пользователя
@@ -107,21 +190,161 @@ func (h *HTTPHandler) UpdateUser(c *gin.Context) {
 // @Produce json
 // @Param id path string true "UUID пользователя"
 // @Success 200 {object} http_response.BaseResponse[any] "Успешное удаление"
-// @Failure 400 {object} http_response.BaseResponse[any] "Невалидные параметры запроса"
-// @Failure 404 {object} http_response.BaseResponse[any] "Пользователь не найден"
-// @Failure 500 {object} http_response.BaseResponse[any] "Внутренняя ошибка сервера"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные параметры запроса"
+// @Failure 404 {object} http_response.ProblemDetails "Пользователь не найден"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
 // @Router /users/{id} [delete]
 func (h *HTTPHandler) DeleteUser(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		http_response.New[any](c, http.StatusBadRequest, false, nil, errs.ErrParamRequired)
+	id, err := parseUserID(c)
+	if err != nil {
+		http_response.Error(c, err)
 		return
 	}
 
-	if err := h.usecase.DeleteUserByID(c.Request.Context(), uuid.MustParse(id)); err != nil {
-		http_response.New[any](c, http.StatusInternalServerError, false, nil, errs.ErrUnknown)
+	if err := h.usecase.DeleteUserByID(c.Request.Context(), id); err != nil {
+		http_response.Error(c, err)
 		return
 	}
 
-	http_response.New[any](c, http.StatusOK, true, nil, nil)
+	http_response.New[any](c, http.StatusOK, true, nil)
+}
+
+// @Summary Повторное обогащение пользователя
+// @Description Заново запрашивает возраст, пол и национальность пользователя.
+// @Description Без force заполняются только отсутствующие поля, с force найденные значения заменяют сохраненные.
+// @Tags Пользователи
+// @Produce json
+// @Param id path string true "UUID пользователя"
+// @Param force query bool false "Перезаписать уже заполненные поля"
+// @Success 200 {object} http_response.BaseResponse[domain.User] "Пользователь после обогащения"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные параметры запроса"
+// @Failure 404 {object} http_response.ProblemDetails "Пользователь не найден"
+// @Failure 409 {object} http_response.ProblemDetails "Пользователь уже обогащается"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
+// @Router /users/{id}/enrich [post]
+func (h *HTTPHandler) EnrichUser(c *gin.Context) {
+	id, err := parseUserID(c)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	force, err := http_dto.GetForceFromQuery(c)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	user, err := h.usecase.EnrichUserByID(c.Request.Context(), id, force)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	http_response.New(c, http.StatusOK, true, user)
+}
+
+// @Summary Повторное обогащение пользователей
+// @Description Ставит пользователей, подходящих под фильтр, в очередь фонового обогащения и возвращает задачу.
+// @Description Без force в очередь попадают только пользователи с незаполненными полями.
+// @Description Нужен хотя бы один фильтр; чтобы обогатить всех пользователей, передайте all=true.
+// @Tags Пользователи
+// @Produce json
+// @Param q query string false "Нечеткий поиск по ФИО с учетом опечаток (например, Ivanof Dmitry)"
+// @Param name query string false "Фильтр по имени (частичное совпадение)"
+// @Param surname query string false "Фильтр по фамилии (частичное совпадение)"
+// @Param patronymic query string false "Фильтр по отчеству (частичное совпадение)"
+// @Param nationality query string false "Фильтр по национальности, несколько значений через запятую (например, RU,KZ)"
+// @Param sex query string false "Фильтр по полу" Enums(MALE, FEMALE)
+// @Param age query int false "Фильтр по возрасту (точное совпадение)" minimum(1) maximum(120)
+// @Param age_min query int false "Минимальный возраст включительно" minimum(1) maximum(120)
+// @Param age_max query int false "Максимальный возраст включительно" minimum(1) maximum(120)
+// @Param has_age query bool false "true — только с известным возрастом, false — только без возраста"
+// @Param created_from query string false "Создан не раньше (RFC 3339 или YYYY-MM-DD, включительно)"
+// @Param created_to query string false "Создан раньше (RFC 3339 или YYYY-MM-DD, не включительно)"
+// @Param updated_since query string false "Обновлен не раньше (RFC 3339 или YYYY-MM-DD)"
+// @Param min_sex_probability query number false "Минимальная уверенность в определении пола" minimum(0) maximum(1)
+// @Param min_nationality_probability query number false "Минимальная уверенность в определении национальности" minimum(0) maximum(1)
+// @Param sex_source query string false "Фильтр по источнику значения пола" Enums(PROVIDER, RULES, MANUAL)
+// @Param nationality_candidate query string false "Фильтр по стране среди всех кандидатов национальности"
+// @Param enrichment_status query string false "Фильтр по статусу обогащения" Enums(pending, processing, done, failed)
+// @Param force query bool false "Перезаписать уже заполненные поля"
+// @Param all query bool false "Подтверждает обогащение всех пользователей, когда фильтры не заданы"
+// @Success 202 {object} http_response.BaseResponse[domain.EnrichmentJob] "Задача создана"
+// @Header 202 {string} Location "Путь к статусу задачи"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные фильтры или не задан ни один фильтр без all=true"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
+// @Router /users/enrich [post]
+func (h *HTTPHandler) EnrichUsers(c *gin.Context) {
+	filter, err := http_dto.GetUserFilterFromQuery(c)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	force, err := http_dto.GetForceFromQuery(c)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	all, err := http_dto.GetAllFromQuery(c)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	input := domain.EnrichUsersInput{
+		Filter: filter,
+		Force:  force,
+		All:    all,
+	}
+
+	job, err := h.usecase.EnrichUsers(c.Request.Context(), input)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/v1/users/enrich/jobs/%s", job.ID))
+	http_response.New(c, http.StatusAccepted, true, job)
+}
+
+// @Summary Статус задачи обогащения
+// @Description Возвращает число пользователей задачи в каждом статусе обогащения
+// @Tags Пользователи
+// @Produce json
+// @Param id path string true "UUID задачи"
+// @Success 200 {object} http_response.BaseResponse[domain.EnrichmentJob] "Успешный ответ"
+// @Failure 400 {object} http_response.ProblemDetails "Невалидные параметры запроса"
+// @Failure 404 {object} http_response.ProblemDetails "Задача не найдена"
+// @Failure 500 {object} http_response.ProblemDetails "Внутренняя ошибка сервера"
+// @Router /users/enrich/jobs/{id} [get]
+func (h *HTTPHandler) GetEnrichmentJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		http_response.Error(c, errs.ErrInvalidJobID)
+		return
+	}
+
+	job, err := h.usecase.GetEnrichmentJob(c.Request.Context(), id)
+	if err != nil {
+		http_response.Error(c, err)
+		return
+	}
+
+	http_response.New(c, http.StatusOK, true, job)
+}
+
+func parseUserID(c *gin.Context) (uuid.UUID, error) {
+	id := c.Param("id")
+	if id == "" {
+		return uuid.Nil, errs.ErrParamRequired
+	}
+
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, errs.ErrInvalidUserID
+	}
+	return parsed, nil
 }
@@ -0,0 +1,28 @@
+package http_handler
+
+import (
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
+	"github.com/FlyKarlik/effectiveMobile/pkg/validation"
+)
+
+func validateInput(input interface{}) error {
+	err := validation.Validate(input)
+	if err == nil {
+		return nil
+	}
+
+	fieldErrs, ok := validation.FieldErrors(err)
+	if !ok {
+		return errs.ErrInvalidRequest
+	}
+
+	violations := make([]errs.Violation, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		violations = append(violations, errs.Violation{
+			Field:   fe.Field,
+			Rule:    fe.Rule,
+			Message: fe.Message,
+		})
+	}
+	return errs.NewValidationError(violations)
+}
@@ -0,0 +1,70 @@
+package http_response
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+const problemTypePrefix = "urn:users-api:problem:"
+
+var statusByCode = map[errs.ErrorCodeEnum]int{
+	errs.CodeUnknown:            http.StatusInternalServerError,
+	errs.CodeInvalidRequest:     http.StatusBadRequest,
+	errs.CodeParamRequired:      http.StatusBadRequest,
+	errs.CodeInvalidUserID:      http.StatusBadRequest,
+	errs.CodeUserNotFound:       http.StatusNotFound,
+	errs.CodeConflict:           http.StatusConflict,
+	errs.CodeUnprocessable:      http.StatusUnprocessableEntity,
+	errs.CodeValidationFailed:   http.StatusUnprocessableEntity,
+	errs.CodeCacheEntryNotFound: http.StatusNotFound,
+
+	errs.CodeEnrichmentJobNotFound: http.StatusNotFound,
+	errs.CodeInvalidJobID:          http.StatusBadRequest,
+	errs.CodeInvalidSort:           http.StatusBadRequest,
+	errs.CodeInvalidCursor:         http.StatusBadRequest,
+	errs.CodeEnrichmentInProgress:  http.StatusConflict,
+}
+
+// ProblemDetails is an RFC 7807 error body extended with the service error code.
+type ProblemDetails struct {
+	Type       string             `json:"type" example:"urn:users-api:problem:user-not-found"`
+	Title      string             `json:"title" example:"Not Found"`
+	Status     int                `json:"status" example:"404"`
+	Detail     string             `json:"detail,omitempty" example:"user not found"`
+	Instance   string             `json:"instance,omitempty" example:"/v1/users/6a1f5c1e-0a8b-4d6e-9c43-6f1b2f2b7f10"`
+	Code       errs.ErrorCodeEnum `json:"code" example:"1"`
+	Violations []errs.Violation   `json:"violations,omitempty"`
+}
+
+func Error(c *gin.Context, err error) {
+	var customErr *errs.CustomError
+	if !errors.As(err, &customErr) {
+		customErr = errs.ErrUnknown
+	}
+
+	status := StatusFromCode(customErr.Code)
+	problem := &ProblemDetails{
+		Type:       problemTypePrefix + customErr.Code.String(),
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     customErr.Message,
+		Instance:   c.Request.URL.Path,
+		Code:       customErr.Code,
+		Violations: customErr.Violations,
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(status, problem)
+}
+
+func StatusFromCode(code errs.ErrorCodeEnum) int {
+	if status, ok := statusByCode[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
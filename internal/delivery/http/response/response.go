@@ -5,18 +5,16 @@ import (
 )
 
 type BaseResponse[T any] struct {
-	Status bool  `json:"status"`
-	Code   int   `json:"code"`
-	Data   T     `json:"data,omitempty"`
-	Error  error `json:"error,omitempty"`
+	Status bool `json:"status"`
+	Code   int  `json:"code"`
+	Data   T    `json:"data,omitempty"`
 }
 
-func New[T any](c *gin.Context, code int, status bool, data T, err error) {
+func New[T any](c *gin.Context, code int, status bool, data T) {
 	obj := &BaseResponse[T]{
 		Code:   code,
 		Status: status,
 		Data:   data,
-		Error:  err,
 	}
 	c.JSON(code, obj)
 }
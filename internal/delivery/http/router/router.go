@@ -43,6 +43,8 @@ func (h *HTTPRouter) InitRouter() *gin.Engine {
 	api := router.Group("v1", h.middleware.JSONMiddleware())
 	{
 		h.registerUserRoutes(api)
+		h.registerEnrichmentRoutes(api)
+		h.registerDiagnosticsRoutes(api)
 	}
 
 	return router
@@ -52,9 +54,28 @@ func (h *HTTPRouter) registerUserRoutes(router *gin.RouterGroup) {
 	userGroup := router.Group("users")
 	{
 		userGroup.GET("/", h.handler.SearchUsers)
+		userGroup.GET("/:id", h.handler.GetUserByID)
 		userGroup.POST("/", h.handler.CreateUser)
 		userGroup.PATCH("/:id", h.handler.UpdateUser)
 		userGroup.DELETE("/:id", h.handler.DeleteUser)
+		userGroup.POST("/:id/enrich", h.handler.EnrichUser)
+		userGroup.POST("/enrich", h.handler.EnrichUsers)
+		userGroup.GET("/enrich/jobs/:id", h.handler.GetEnrichmentJob)
+	}
+}
+
+func (h *HTTPRouter) registerEnrichmentRoutes(router *gin.RouterGroup) {
+	enrichmentGroup := router.Group("enrichment")
+	{
+		enrichmentGroup.DELETE("/cache", h.handler.PurgeEnrichmentCache)
+		enrichmentGroup.POST("/cache/warm", h.handler.WarmEnrichmentCache)
+	}
+}
+
+func (h *HTTPRouter) registerDiagnosticsRoutes(router *gin.RouterGroup) {
+	diagnosticsGroup := router.Group("diagnostics")
+	{
+		diagnosticsGroup.GET("/enrichment", h.handler.GetEnrichmentProviders)
 	}
 }
 
@@ -0,0 +1,83 @@
+package enrichment_worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/FlyKarlik/effectiveMobile/config"
+	"github.com/FlyKarlik/effectiveMobile/internal/usecase"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+)
+
+// EnrichmentWorker runs a pool of goroutines that enrich users created in
+// async mode or queued for re-enrichment. Each goroutine keeps taking batches
+// of pending users while there are any and sleeps for the poll interval once
+// the queue is empty.
+type EnrichmentWorker struct {
+	logger       logger.Logger
+	usecase      *usecase.Usecase
+	count        int
+	pollInterval time.Duration
+	wg           sync.WaitGroup
+}
+
+func New(logger logger.Logger, usecase *usecase.Usecase, cfg *config.Enrichment) *EnrichmentWorker {
+	return &EnrichmentWorker{
+		logger:       logger,
+		usecase:      usecase,
+		count:        cfg.WorkerCount,
+		pollInterval: cfg.WorkerPollInterval,
+	}
+}
+
+// Start launches the pool. The workers stop when ctx is cancelled; Wait
+// blocks until the user they were processing has been stored or rolled back.
+func (w *EnrichmentWorker) Start(ctx context.Context) {
+	const layer = "worker"
+	const method = "Start"
+
+	w.logger.Info(layer, method, "Starting enrichment workers", "count", w.count, "poll_interval", w.pollInterval)
+
+	for i := 0; i < w.count; i++ {
+		w.wg.Add(1)
+		go func(id int) {
+			defer w.wg.Done()
+			w.run(ctx, id)
+		}(i)
+	}
+}
+
+func (w *EnrichmentWorker) Wait() {
+	w.wg.Wait()
+}
+
+func (w *EnrichmentWorker) run(ctx context.Context, id int) {
+	const layer = "worker"
+	const method = "run"
+
+	w.logger.Debug(layer, method, "worker started", "worker_id", id)
+	defer w.logger.Debug(layer, method, "worker stopped", "worker_id", id)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		processed, err := w.usecase.EnrichPendingUsers(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error(layer, method, "failed to enrich pending users", err, "worker_id", id)
+		}
+
+		if processed > 0 {
+			timer.Reset(0)
+			continue
+		}
+		timer.Reset(w.pollInterval)
+	}
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ProviderState struct {
+	Provider            string     `json:"provider"`
+	BreakerState        string     `json:"breaker_state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	Requests            int64      `json:"requests"`
+	Retries             int64      `json:"retries"`
+	RateLimited         int64      `json:"rate_limited"`
+	Failures            int64      `json:"failures"`
+	Rejected            int64      `json:"rejected"`
+}
+
+// EnrichmentQuery describes a single provider lookup. CountryID is an optional
+// ISO 3166-1 alpha-2 hint that makes the prediction local to that country.
+// Surname and Patronymic are only used by local rule-based drivers; remote
+// providers look at the name alone.
+type EnrichmentQuery struct {
+	Name       string
+	CountryID  string
+	Surname    string
+	Patronymic string
+}
+
+type AgePrediction struct {
+	Age   int   `json:"age"`
+	Count int64 `json:"count"`
+}
+
+type SexPrediction struct {
+	Sex         SexEnum `json:"sex"`
+	Probability float64 `json:"probability"`
+	Count       int64   `json:"count"`
+}
+
+type NationalityPrediction struct {
+	CountryID   string                 `json:"country_id"`
+	Probability float64                `json:"probability"`
+	Count       int64                  `json:"count"`
+	Candidates  []NationalityCandidate `json:"candidates,omitempty"`
+}
+
+type NationalityCandidate struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+type EnrichmentCacheKey struct {
+	Name        string
+	CountryHint string
+	Attribute   EnrichmentAttributeEnum
+}
+
+type EnrichmentCacheEntry struct {
+	Key       EnrichmentCacheKey
+	Value     json.RawMessage
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type EnrichmentCachePurgeFilter struct {
+	Name        *string
+	ExpiredOnly bool
+}
+
+type WarmEnrichmentCacheInput struct {
+	Names       []string `json:"names" validate:"required,min=1,max=100,dive,required,max=100"`
+	CountryHint *string  `json:"country_hint,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+}
+
+type WarmEnrichmentCacheResult struct {
+	Warmed int `json:"warmed"`
+	Failed int `json:"failed"`
+}
+
+type PurgeEnrichmentCacheResult struct {
+	Purged int64 `json:"purged"`
+}
+
+// EnrichUsersInput selects users for re-enrichment. Without Force only users
+// missing age, sex or nationality are queued and only the missing attributes
+// are filled; with Force every found attribute replaces the stored one. An
+// empty filter is only accepted with All, so that a forgotten filter does not
+// queue every user.
+type EnrichUsersInput struct {
+	Filter UserFilter
+	Force  bool
+	All    bool
+}
+
+// EnrichmentJob is a bulk re-enrichment request. The status counters cover
+// every user the job queued; a user queued again by a later job counts in
+// both until it is enriched.
+type EnrichmentJob struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Force     bool      `json:"force"`
+	Total     int64     `json:"total"`
+	Pending   int64     `json:"pending"`
+	Done      int64     `json:"done"`
+	Failed    int64     `json:"failed"`
+}
@@ -6,3 +6,46 @@ const (
 	FemaleSexEnum SexEnum = "FEMALE"
 	MaleSexEnum   SexEnum = "MALE"
 )
+
+type SexSourceEnum string
+
+const (
+	ProviderSexSource SexSourceEnum = "PROVIDER"
+	RulesSexSource    SexSourceEnum = "RULES"
+	ManualSexSource   SexSourceEnum = "MANUAL"
+)
+
+type EnrichmentStatusEnum string
+
+const (
+	PendingEnrichmentStatus    EnrichmentStatusEnum = "pending"
+	ProcessingEnrichmentStatus EnrichmentStatusEnum = "processing"
+	DoneEnrichmentStatus       EnrichmentStatusEnum = "done"
+	FailedEnrichmentStatus     EnrichmentStatusEnum = "failed"
+)
+
+type SexRulesModeEnum string
+
+const (
+	DisabledSexRulesMode SexRulesModeEnum = "disabled"
+	FallbackSexRulesMode SexRulesModeEnum = "fallback"
+	PrimarySexRulesMode  SexRulesModeEnum = "primary"
+)
+
+type EnrichmentAttributeEnum string
+
+const (
+	AgeEnrichmentAttribute         EnrichmentAttributeEnum = "AGE"
+	SexEnrichmentAttribute         EnrichmentAttributeEnum = "SEX"
+	NationalityEnrichmentAttribute EnrichmentAttributeEnum = "NATIONALITY"
+)
+
+// CountModeEnum tells a search how to compute the total: exactly, from the
+// planner's row estimate, or not at all.
+type CountModeEnum string
+
+const (
+	ExactCountMode    CountModeEnum = "exact"
+	EstimateCountMode CountModeEnum = "estimate"
+	NoneCountMode     CountModeEnum = "none"
+)
@@ -1,6 +1,32 @@
 package domain
 
+import "github.com/google/uuid"
+
+// Pagination is either offset based or, when Cursor is set, keyset based; a
+// cursor takes precedence over the offset. After is the decoded cursor. Count
+// defaults to an exact total.
 type Pagination struct {
 	Limit  int64
 	Offset int64
+	Cursor string
+	After  *Keyset
+	Count  CountModeEnum
+}
+
+// Keyset is the position of a row in a sorted listing: the values of its sort
+// fields in sort order, nil for NULL, and its id as the final tie-breaker.
+type Keyset struct {
+	Values []*string
+	ID     uuid.UUID
 }
+
+// SortField is one ordering key; Field is the public attribute name as used
+// in the sort query parameter.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Sort lists ordering keys by priority. Which fields are sortable is decided
+// by the repository.
+type Sort []SortField
@@ -7,40 +7,120 @@ import (
 )
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreateAt    time.Time `json:"created_at"`
-	UpdateAt    time.Time `json:"updated_at"`
-	Name        string    `json:"name"`
-	Surname     string    `json:"surname"`
-	Nationality *string   `json:"nationality,omitempty"`
-	Patronymic  *string   `json:"patronymic,omitempty"`
-	Sex         *SexEnum  `json:"sex,omitempty"`
-	Age         *int64    `json:"age,omitempty"`
+	ID                     uuid.UUID      `json:"id"`
+	CreateAt               time.Time      `json:"created_at"`
+	UpdateAt               time.Time      `json:"updated_at"`
+	Name                   string         `json:"name"`
+	Surname                string         `json:"surname"`
+	Nationality            *string        `json:"nationality,omitempty"`
+	Patronymic             *string        `json:"patronymic,omitempty"`
+	Sex                    *SexEnum       `json:"sex,omitempty"`
+	Age                    *int64         `json:"age,omitempty"`
+	SexProbability         *float64       `json:"sex_probability,omitempty"`
+	SexSource              *SexSourceEnum `json:"sex_source,omitempty"`
+	NationalityProbability *float64       `json:"nationality_probability,omitempty"`
+	EnrichmentSampleCount  *int64         `json:"enrichment_sample_count,omitempty"`
+
+	EnrichmentStatus   EnrichmentStatusEnum `json:"enrichment_status"`
+	EnrichmentAttempts int64                `json:"enrichment_attempts"`
+	EnrichmentError    *string              `json:"enrichment_error,omitempty"`
+	EnrichmentJobID    *uuid.UUID           `json:"enrichment_job_id,omitempty"`
+	EnrichmentForce    bool                 `json:"-"`
+	CountryHint        *string              `json:"-"`
+
+	// Score is the full-name search relevance and is only set for results
+	// of a search by q.
+	Score *float64 `json:"score,omitempty"`
+
+	NationalityCandidates []NationalityCandidate `json:"nationality_candidates,omitempty"`
 }
 
 type CreateUserInput struct {
-	Name        string   `json:"name" binding:"required"`
-	Surname     string   `json:"surname" binding:"required"`
-	Patronymic  *string  `json:"patronymic,omitempty"`
-	Nationality *string  `json:"-"`
-	Age         *int64   `json:"-"`
-	Sex         *SexEnum `json:"-"`
+	Name        string   `json:"name" validate:"required,max=100"`
+	Surname     string   `json:"surname" validate:"required,max=100"`
+	Patronymic  *string  `json:"patronymic,omitempty" validate:"omitempty,max=100"`
+	CountryHint *string  `json:"country_hint,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	Nationality *string  `json:"-" validate:"omitempty,iso3166_1_alpha2"`
+	Age         *int64   `json:"-" validate:"omitempty,min=1,max=119"`
+	Sex         *SexEnum `json:"-" validate:"omitempty,oneof=MALE FEMALE"`
+
+	SexProbability         *float64       `json:"-" validate:"omitempty,min=0,max=1"`
+	SexSource              *SexSourceEnum `json:"-" validate:"omitempty,oneof=PROVIDER RULES MANUAL"`
+	NationalityProbability *float64       `json:"-" validate:"omitempty,min=0,max=1"`
+	EnrichmentSampleCount  *int64         `json:"-" validate:"omitempty,min=0"`
+
+	EnrichmentStatus   EnrichmentStatusEnum `json:"-"`
+	EnrichmentAttempts int64                `json:"-"`
+	EnrichmentError    *string              `json:"-"`
+
+	NationalityCandidates []NationalityCandidate `json:"-"`
+}
+
+// UserEnrichment is the outcome of one background enrichment attempt. Only
+// the attributes that were found are set; the status fields are always
+// written. NextAttemptAt is used while the status stays pending.
+type UserEnrichment struct {
+	Nationality            *string
+	Age                    *int64
+	Sex                    *SexEnum
+	SexProbability         *float64
+	SexSource              *SexSourceEnum
+	NationalityProbability *float64
+	EnrichmentSampleCount  *int64
+	NationalityCandidates  []NationalityCandidate
+
+	// Force replaces stored attributes. Without it the found ones only fill
+	// attributes that are still empty when the result is written back.
+	Force         bool
+	Status        EnrichmentStatusEnum
+	Error         *string
+	NextAttemptAt time.Time
 }
 
 type UpdateUserInput struct {
-	Name        *string  `json:"name,omitempty"`
-	Surname     *string  `json:"surname,omitempty"`
-	Patronymic  *string  `json:"patronymic,omitempty"`
-	Nationality *string  `json:"nationality,omitempty"`
-	Age         *int64   `json:"age,omitempty"`
-	Sex         *SexEnum `json:"sex,omitempty"`
+	Name        *string  `json:"name,omitempty" validate:"omitnil,min=1,max=100"`
+	Surname     *string  `json:"surname,omitempty" validate:"omitnil,min=1,max=100"`
+	Patronymic  *string  `json:"patronymic,omitempty" validate:"omitempty,max=100"`
+	Nationality *string  `json:"nationality,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	Age         *int64   `json:"age,omitempty" validate:"omitempty,min=1,max=119"`
+	Sex         *SexEnum `json:"sex,omitempty" validate:"omitempty,oneof=MALE FEMALE"`
 }
 
+// UserFilter narrows a user search. Range bounds are inclusive except
+// CreatedTo, which is exclusive so that consecutive ranges do not overlap.
 type UserFilter struct {
-	Name        *string
-	Surname     *string
-	Patronymic  *string
-	Nationality *string
-	Sex         *SexEnum
-	Age         *int64
+	// Query is a full name, possibly misspelled or partial, matched by
+	// trigram similarity across name, surname and patronymic.
+	Query                     *string
+	Name                      *string
+	Surname                   *string
+	Patronymic                *string
+	Nationalities             []string
+	Sex                       *SexEnum
+	Age                       *int64
+	AgeMin                    *int64
+	AgeMax                    *int64
+	HasAge                    *bool
+	CreatedFrom               *time.Time
+	CreatedTo                 *time.Time
+	UpdatedSince              *time.Time
+	MinSexProbability         *float64
+	MinNationalityProbability *float64
+	NationalityCandidate      *string
+	SexSource                 *SexSourceEnum
+	EnrichmentStatus          *EnrichmentStatusEnum
+}
+
+// UserPage is one page of a user search. Total is nil when it was not
+// counted, and Next is nil on the last page.
+type UserPage struct {
+	Users []User
+	Total *int64
+	Next  *Keyset
+}
+
+// UserInclude lists optional relations that are loaded only when the client
+// asks for them via the include query parameter.
+type UserInclude struct {
+	NationalityCandidates bool
 }
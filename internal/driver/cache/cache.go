@@ -0,0 +1,267 @@
+package cache_drver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/FlyKarlik/effectiveMobile/config"
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+	"github.com/FlyKarlik/effectiveMobile/pkg/lru"
+)
+
+type ICacheStore interface {
+	GetCacheEntry(ctx context.Context, key domain.EnrichmentCacheKey) (domain.EnrichmentCacheEntry, error)
+	SetCacheEntry(ctx context.Context, entry domain.EnrichmentCacheEntry) error
+	PurgeCache(ctx context.Context, filter domain.EnrichmentCachePurgeFilter) (int64, error)
+}
+
+type IEnrichmentCache interface {
+	PurgeCache(ctx context.Context, filter domain.EnrichmentCachePurgeFilter) (int64, error)
+}
+
+// Cache is the two-level enrichment cache: an in-memory LRU in front of the
+// persistent store. One Cache can wrap several drivers, e.g. one provider
+// chain per attribute, while keeping a single memory budget and purge point.
+type Cache struct {
+	logger      logger.Logger
+	store       ICacheStore
+	memory      *lru.Cache[domain.EnrichmentCacheKey, domain.EnrichmentCacheEntry]
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+type cachedUserDriver struct {
+	user_drver.IUserDriver
+	cache *Cache
+}
+
+func New(logger logger.Logger, store ICacheStore, cfg *config.Enrichment) *Cache {
+	return &Cache{
+		logger:      logger,
+		store:       store,
+		memory:      lru.New[domain.EnrichmentCacheKey, domain.EnrichmentCacheEntry](cfg.CacheMemorySize),
+		ttl:         cfg.CacheTTL,
+		negativeTTL: cfg.CacheNegativeTTL,
+	}
+}
+
+// Wrap returns a driver that answers from the cache and falls through to next
+// on a miss, storing whatever next returns.
+func (c *Cache) Wrap(next user_drver.IUserDriver) user_drver.IUserDriver {
+	return &cachedUserDriver{
+		IUserDriver: next,
+		cache:       c,
+	}
+}
+
+func (c *cachedUserDriver) GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error) {
+	key := newKey(query, domain.AgeEnrichmentAttribute)
+	return cached(ctx, c.cache, "GetUserAge", key, func(ctx context.Context) (domain.AgePrediction, error) {
+		return c.IUserDriver.GetUserAge(ctx, query)
+	})
+}
+
+func (c *cachedUserDriver) GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error) {
+	key := newKey(domain.EnrichmentQuery{Name: name}, domain.NationalityEnrichmentAttribute)
+	return cached(ctx, c.cache, "GetUserNationality", key, func(ctx context.Context) (domain.NationalityPrediction, error) {
+		return c.IUserDriver.GetUserNationality(ctx, name)
+	})
+}
+
+func (c *cachedUserDriver) GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error) {
+	key := newKey(query, domain.SexEnrichmentAttribute)
+	return cached(ctx, c.cache, "GetUserSex", key, func(ctx context.Context) (domain.SexPrediction, error) {
+		return c.IUserDriver.GetUserSex(ctx, query)
+	})
+}
+
+func (c *cachedUserDriver) GetUsersAgeBatch(ctx context.Context, names []string, countryID string) (map[string]domain.AgePrediction, error) {
+	return cachedBatch(ctx, c.cache, "GetUsersAgeBatch", names, func(name string) domain.EnrichmentCacheKey {
+		return newKey(domain.EnrichmentQuery{Name: name, CountryID: countryID}, domain.AgeEnrichmentAttribute)
+	}, func(ctx context.Context, misses []string) (map[string]domain.AgePrediction, error) {
+		return c.IUserDriver.GetUsersAgeBatch(ctx, misses, countryID)
+	})
+}
+
+func (c *cachedUserDriver) GetUsersNationalityBatch(ctx context.Context, names []string) (map[string]domain.NationalityPrediction, error) {
+	return cachedBatch(ctx, c.cache, "GetUsersNationalityBatch", names, func(name string) domain.EnrichmentCacheKey {
+		return newKey(domain.EnrichmentQuery{Name: name}, domain.NationalityEnrichmentAttribute)
+	}, func(ctx context.Context, misses []string) (map[string]domain.NationalityPrediction, error) {
+		return c.IUserDriver.GetUsersNationalityBatch(ctx, misses)
+	})
+}
+
+func (c *cachedUserDriver) GetUsersSexBatch(ctx context.Context, names []string, countryID string) (map[string]domain.SexPrediction, error) {
+	return cachedBatch(ctx, c.cache, "GetUsersSexBatch", names, func(name string) domain.EnrichmentCacheKey {
+		return newKey(domain.EnrichmentQuery{Name: name, CountryID: countryID}, domain.SexEnrichmentAttribute)
+	}, func(ctx context.Context, misses []string) (map[string]domain.SexPrediction, error) {
+		return c.IUserDriver.GetUsersSexBatch(ctx, misses, countryID)
+	})
+}
+
+func (c *Cache) PurgeCache(ctx context.Context, filter domain.EnrichmentCachePurgeFilter) (int64, error) {
+	const layer = "driver"
+	const method = "PurgeCache"
+
+	c.logger.Debug(layer, method, "started", "filter", filter)
+
+	if filter.Name != nil {
+		normalized := user_drver.NormalizeName(*filter.Name)
+		filter.Name = &normalized
+	}
+
+	purged, err := c.store.PurgeCache(ctx, filter)
+	if err != nil {
+		c.logger.Error(layer, method, "failed to purge persistent cache", err, "filter", filter)
+		return 0, err
+	}
+
+	now := time.Now()
+	evicted := c.memory.RemoveIf(func(key domain.EnrichmentCacheKey, entry domain.EnrichmentCacheEntry) bool {
+		if filter.Name != nil && key.Name != *filter.Name {
+			return false
+		}
+		return !filter.ExpiredOnly || !entry.ExpiresAt.After(now)
+	})
+
+	c.logger.Debug(layer, method, "successfully completed", "purged", purged, "evicted_from_memory", evicted)
+	return purged, nil
+}
+
+func cached[T any](ctx context.Context, c *Cache, method string, key domain.EnrichmentCacheKey, fetch func(ctx context.Context) (T, error)) (T, error) {
+	const layer = "driver"
+
+	var value T
+	if entry, ok := c.lookup(ctx, method, key); ok {
+		err := json.Unmarshal(entry.Value, &value)
+		if err == nil {
+			c.logger.Debug(layer, method, "cache hit", "key", key)
+			return value, nil
+		}
+		c.logger.Warn(layer, method, "failed to decode cache entry", err, "key", key)
+	}
+
+	c.logger.Debug(layer, method, "cache miss", "key", key)
+
+	value, err := fetch(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	c.save(ctx, method, key, value)
+	return value, nil
+}
+
+// cachedBatch answers what it can from the cache and sends only the misses to
+// the wrapped driver, so warm names cost no provider quota at all.
+func cachedBatch[T any](ctx context.Context, c *Cache, method string, names []string, keyOf func(name string) domain.EnrichmentCacheKey, fetch func(ctx context.Context, misses []string) (map[string]T, error)) (map[string]T, error) {
+	const layer = "driver"
+
+	result := make(map[string]T, len(names))
+	misses := make([]string, 0, len(names))
+	for _, name := range names {
+		key := keyOf(name)
+		if entry, ok := c.lookup(ctx, method, key); ok {
+			var value T
+			err := json.Unmarshal(entry.Value, &value)
+			if err == nil {
+				result[name] = value
+				continue
+			}
+			c.logger.Warn(layer, method, "failed to decode cache entry", err, "key", key)
+		}
+		misses = append(misses, name)
+	}
+
+	c.logger.Debug(layer, method, "batch cache lookup", "hits", len(result), "misses", len(misses))
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	fetched, err := fetch(ctx, misses)
+	for name, value := range fetched {
+		c.save(ctx, method, keyOf(name), value)
+		result[name] = value
+	}
+
+	return result, err
+}
+
+func (c *Cache) lookup(ctx context.Context, method string, key domain.EnrichmentCacheKey) (domain.EnrichmentCacheEntry, bool) {
+	const layer = "driver"
+
+	now := time.Now()
+	if entry, ok := c.memory.Get(key); ok {
+		if entry.ExpiresAt.After(now) {
+			return entry, true
+		}
+		c.memory.Remove(key)
+	}
+
+	entry, err := c.store.GetCacheEntry(ctx, key)
+	if err != nil {
+		if !errors.Is(err, errs.ErrCacheEntryNotFound) {
+			c.logger.Warn(layer, method, "failed to read persistent cache", err, "key", key)
+		}
+		return domain.EnrichmentCacheEntry{}, false
+	}
+
+	c.memory.Add(key, entry)
+	return entry, true
+}
+
+func (c *Cache) save(ctx context.Context, method string, key domain.EnrichmentCacheKey, value interface{}) {
+	const layer = "driver"
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		c.logger.Warn(layer, method, "failed to encode cache entry", err, "key", key)
+		return
+	}
+
+	ttl := c.ttl
+	if isEmpty(value) {
+		ttl = c.negativeTTL
+	}
+
+	now := time.Now()
+	entry := domain.EnrichmentCacheEntry{
+		Key:       key,
+		Value:     raw,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	c.memory.Add(key, entry)
+	if err := c.store.SetCacheEntry(ctx, entry); err != nil {
+		c.logger.Warn(layer, method, "failed to write persistent cache", err, "key", key)
+	}
+}
+
+func newKey(query domain.EnrichmentQuery, attribute domain.EnrichmentAttributeEnum) domain.EnrichmentCacheKey {
+	return domain.EnrichmentCacheKey{
+		Name:        user_drver.NormalizeName(query.Name),
+		CountryHint: strings.ToUpper(query.CountryID),
+		Attribute:   attribute,
+	}
+}
+
+// isEmpty reports whether value is an answer without a prediction. Providers
+// learn new names over time, so such answers are not kept for long.
+func isEmpty(value interface{}) bool {
+	switch prediction := value.(type) {
+	case domain.AgePrediction:
+		return prediction.Age == 0
+	case domain.NationalityPrediction:
+		return prediction.CountryID == ""
+	case domain.SexPrediction:
+		return prediction.Sex == ""
+	}
+	return false
+}
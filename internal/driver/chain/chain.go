@@ -0,0 +1,168 @@
+package chain_drver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+)
+
+var (
+	ErrNoProviders = errors.New("no enrichment providers configured")
+)
+
+// Link is one named provider in a chain; the name only shows up in logs.
+type Link struct {
+	Name   string
+	Driver user_drver.IUserDriver
+}
+
+// fallbackUserDriver asks its links in order and returns the first non-empty
+// prediction. A link that fails or knows nothing about a name hands over to
+// the next one; the error is only returned when no link produced an answer.
+type fallbackUserDriver struct {
+	logger logger.Logger
+	links  []Link
+}
+
+func NewFallback(logger logger.Logger, links []Link) *fallbackUserDriver {
+	return &fallbackUserDriver{
+		logger: logger,
+		links:  links,
+	}
+}
+
+func (f *fallbackUserDriver) GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error) {
+	return first(ctx, f, "GetUserAge", func(link user_drver.IUserDriver) (domain.AgePrediction, error) {
+		return link.GetUserAge(ctx, query)
+	}, isEmptyAge)
+}
+
+func (f *fallbackUserDriver) GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error) {
+	return first(ctx, f, "GetUserNationality", func(link user_drver.IUserDriver) (domain.NationalityPrediction, error) {
+		return link.GetUserNationality(ctx, name)
+	}, isEmptyNationality)
+}
+
+func (f *fallbackUserDriver) GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error) {
+	return first(ctx, f, "GetUserSex", func(link user_drver.IUserDriver) (domain.SexPrediction, error) {
+		return link.GetUserSex(ctx, query)
+	}, isEmptySex)
+}
+
+func (f *fallbackUserDriver) GetUsersAgeBatch(ctx context.Context, names []string, countryID string) (map[string]domain.AgePrediction, error) {
+	return firstBatch(ctx, f, "GetUsersAgeBatch", names, func(link user_drver.IUserDriver, names []string) (map[string]domain.AgePrediction, error) {
+		return link.GetUsersAgeBatch(ctx, names, countryID)
+	}, isEmptyAge)
+}
+
+func (f *fallbackUserDriver) GetUsersNationalityBatch(ctx context.Context, names []string) (map[string]domain.NationalityPrediction, error) {
+	return firstBatch(ctx, f, "GetUsersNationalityBatch", names, func(link user_drver.IUserDriver, names []string) (map[string]domain.NationalityPrediction, error) {
+		return link.GetUsersNationalityBatch(ctx, names)
+	}, isEmptyNationality)
+}
+
+func (f *fallbackUserDriver) GetUsersSexBatch(ctx context.Context, names []string, countryID string) (map[string]domain.SexPrediction, error) {
+	return firstBatch(ctx, f, "GetUsersSexBatch", names, func(link user_drver.IUserDriver, names []string) (map[string]domain.SexPrediction, error) {
+		return link.GetUsersSexBatch(ctx, names, countryID)
+	}, isEmptySex)
+}
+
+func (f *fallbackUserDriver) GetProvidersState(ctx context.Context) []domain.ProviderState {
+	var states []domain.ProviderState
+	for _, link := range f.links {
+		states = append(states, link.Driver.GetProvidersState(ctx)...)
+	}
+	return states
+}
+
+func first[T any](ctx context.Context, f *fallbackUserDriver, method string, call func(link user_drver.IUserDriver) (T, error), isEmpty func(T) bool) (T, error) {
+	const layer = "driver"
+
+	var (
+		result   T
+		answered bool
+		lastErr  = ErrNoProviders
+	)
+
+	for _, link := range f.links {
+		value, err := call(link.Driver)
+		if err != nil {
+			f.logger.Warn(layer, method, "provider link failed", err, "link", link.Name)
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		result, answered = value, true
+		if !isEmpty(value) {
+			f.logger.Debug(layer, method, "provider link answered", "link", link.Name)
+			return value, nil
+		}
+	}
+
+	if answered {
+		return result, nil
+	}
+	return result, lastErr
+}
+
+func firstBatch[T any](ctx context.Context, f *fallbackUserDriver, method string, names []string, call func(link user_drver.IUserDriver, names []string) (map[string]T, error), isEmpty func(T) bool) (map[string]T, error) {
+	const layer = "driver"
+
+	result := make(map[string]T, len(names))
+	pending := names
+	var errList []error
+
+	for _, link := range f.links {
+		if len(pending) == 0 || ctx.Err() != nil {
+			break
+		}
+
+		values, err := call(link.Driver, pending)
+		if err != nil {
+			f.logger.Warn(layer, method, "provider link failed for some names", err, "link", link.Name)
+			errList = append(errList, err)
+		}
+
+		next := pending[:0:0]
+		for _, name := range pending {
+			value, ok := values[name]
+			if ok {
+				result[name] = value
+			}
+			if !ok || isEmpty(value) {
+				next = append(next, name)
+			}
+		}
+		pending = next
+	}
+
+	// Errors only matter for names that no link answered at all.
+	for _, name := range pending {
+		if _, ok := result[name]; ok {
+			continue
+		}
+		if len(errList) == 0 {
+			errList = append(errList, ErrNoProviders)
+		}
+		return result, errors.Join(errList...)
+	}
+	return result, nil
+}
+
+func isEmptyAge(p domain.AgePrediction) bool {
+	return p.Age == 0
+}
+
+func isEmptyNationality(p domain.NationalityPrediction) bool {
+	return p.CountryID == ""
+}
+
+func isEmptySex(p domain.SexPrediction) bool {
+	return p.Sex == ""
+}
@@ -0,0 +1,58 @@
+package chain_drver
+
+import (
+	"context"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
+)
+
+// routerUserDriver sends every attribute to its own provider chain. Provider
+// diagnostics come from the remote driver alone so that a provider shared by
+// several chains is reported once.
+type routerUserDriver struct {
+	age         user_drver.IUserDriver
+	sex         user_drver.IUserDriver
+	nationality user_drver.IUserDriver
+	remote      user_drver.IUserDriver
+}
+
+func NewRouter(age, sex, nationality, remote user_drver.IUserDriver) *routerUserDriver {
+	return &routerUserDriver{
+		age:         age,
+		sex:         sex,
+		nationality: nationality,
+		remote:      remote,
+	}
+}
+
+func (r *routerUserDriver) GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error) {
+	return r.age.GetUserAge(ctx, query)
+}
+
+func (r *routerUserDriver) GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error) {
+	return r.nationality.GetUserNationality(ctx, name)
+}
+
+func (r *routerUserDriver) GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error) {
+	return r.sex.GetUserSex(ctx, query)
+}
+
+func (r *routerUserDriver) GetUsersAgeBatch(ctx context.Context, names []string, countryID string) (map[string]domain.AgePrediction, error) {
+	return r.age.GetUsersAgeBatch(ctx, names, countryID)
+}
+
+func (r *routerUserDriver) GetUsersNationalityBatch(ctx context.Context, names []string) (map[string]domain.NationalityPrediction, error) {
+	return r.nationality.GetUsersNationalityBatch(ctx, names)
+}
+
+func (r *routerUserDriver) GetUsersSexBatch(ctx context.Context, names []string, countryID string) (map[string]domain.SexPrediction, error) {
+	return r.sex.GetUsersSexBatch(ctx, names, countryID)
+}
+
+func (r *routerUserDriver) GetProvidersState(ctx context.Context) []domain.ProviderState {
+	if r.remote == nil {
+		return []domain.ProviderState{}
+	}
+	return r.remote.GetProvidersState(ctx)
+}
@@ -0,0 +1,291 @@
+package dataset_drver
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("dataset must be a .csv or .json file")
+)
+
+// record is one row of the name statistics dataset. CSV files carry the same
+// fields as columns, identified by a header row in any order; JSON files hold
+// an array of objects. A row without country_id is the global statistic for
+// the name, rows with one are localized. Every row that has a nationality
+// becomes a nationality candidate for its name, so a name may span several
+// rows.
+type record struct {
+	Name                   string  `json:"name"`
+	CountryID              string  `json:"country_id"`
+	Age                    int     `json:"age"`
+	Sex                    string  `json:"sex"`
+	SexProbability         float64 `json:"sex_probability"`
+	Nationality            string  `json:"nationality"`
+	NationalityProbability float64 `json:"nationality_probability"`
+	Count                  int64   `json:"count"`
+}
+
+type recordKey struct {
+	name      string
+	countryID string
+}
+
+// datasetUserDriver answers from a local name statistics file loaded once at
+// startup, so enrichment keeps working without network access.
+type datasetUserDriver struct {
+	logger        logger.Logger
+	records       map[recordKey]record
+	nationalities map[string]domain.NationalityPrediction
+}
+
+func New(logger logger.Logger, path string) (*datasetUserDriver, error) {
+	const layer = "driver"
+	const method = "New"
+
+	records, err := load(path)
+	if err != nil {
+		return nil, fmt.Errorf("load dataset %s: %w", path, err)
+	}
+
+	d := &datasetUserDriver{
+		logger:        logger,
+		records:       make(map[recordKey]record, len(records)),
+		nationalities: make(map[string]domain.NationalityPrediction),
+	}
+
+	for _, r := range records {
+		name := user_drver.NormalizeName(r.Name)
+		if name == "" {
+			continue
+		}
+		key := recordKey{name: name, countryID: strings.ToUpper(r.CountryID)}
+		d.records[key] = merge(d.records[key], r)
+
+		if r.Nationality == "" {
+			continue
+		}
+		prediction := d.nationalities[name]
+		prediction.Candidates = append(prediction.Candidates, domain.NationalityCandidate{
+			CountryID:   strings.ToUpper(r.Nationality),
+			Probability: r.NationalityProbability,
+		})
+		prediction.Count = max(prediction.Count, r.Count)
+		d.nationalities[name] = prediction
+	}
+
+	for name, prediction := range d.nationalities {
+		sort.SliceStable(prediction.Candidates, func(i, j int) bool {
+			return prediction.Candidates[i].Probability > prediction.Candidates[j].Probability
+		})
+		prediction.CountryID = prediction.Candidates[0].CountryID
+		prediction.Probability = prediction.Candidates[0].Probability
+		d.nationalities[name] = prediction
+	}
+
+	logger.Info(layer, method, "dataset loaded", "path", path, "records", len(d.records), "names_with_nationality", len(d.nationalities))
+	return d, nil
+}
+
+func (d *datasetUserDriver) GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error) {
+	r, ok := d.lookup(query.Name, query.CountryID)
+	if !ok {
+		return domain.AgePrediction{}, nil
+	}
+	return domain.AgePrediction{Age: r.Age, Count: r.Count}, nil
+}
+
+func (d *datasetUserDriver) GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error) {
+	return d.nationalities[user_drver.NormalizeName(name)], nil
+}
+
+func (d *datasetUserDriver) GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error) {
+	r, ok := d.lookup(query.Name, query.CountryID)
+	if !ok {
+		return domain.SexPrediction{}, nil
+	}
+	return domain.SexPrediction{
+		Sex:         toSexEnum(r.Sex),
+		Probability: r.SexProbability,
+		Count:       r.Count,
+	}, nil
+}
+
+func (d *datasetUserDriver) GetUsersAgeBatch(ctx context.Context, names []string, countryID string) (map[string]domain.AgePrediction, error) {
+	predictions := make(map[string]domain.AgePrediction, len(names))
+	for _, name := range names {
+		if r, ok := d.lookup(name, countryID); ok {
+			predictions[name] = domain.AgePrediction{Age: r.Age, Count: r.Count}
+		}
+	}
+	return predictions, nil
+}
+
+func (d *datasetUserDriver) GetUsersNationalityBatch(ctx context.Context, names []string) (map[string]domain.NationalityPrediction, error) {
+	predictions := make(map[string]domain.NationalityPrediction, len(names))
+	for _, name := range names {
+		if prediction, ok := d.nationalities[user_drver.NormalizeName(name)]; ok {
+			predictions[name] = prediction
+		}
+	}
+	return predictions, nil
+}
+
+func (d *datasetUserDriver) GetUsersSexBatch(ctx context.Context, names []string, countryID string) (map[string]domain.SexPrediction, error) {
+	predictions := make(map[string]domain.SexPrediction, len(names))
+	for _, name := range names {
+		if r, ok := d.lookup(name, countryID); ok {
+			predictions[name] = domain.SexPrediction{
+				Sex:         toSexEnum(r.Sex),
+				Probability: r.SexProbability,
+				Count:       r.Count,
+			}
+		}
+	}
+	return predictions, nil
+}
+
+func (d *datasetUserDriver) GetProvidersState(ctx context.Context) []domain.ProviderState {
+	return nil
+}
+
+// lookup prefers the localized statistic and falls back to the global one.
+func (d *datasetUserDriver) lookup(name string, countryID string) (record, bool) {
+	name = user_drver.NormalizeName(name)
+	if countryID != "" {
+		if r, ok := d.records[recordKey{name: name, countryID: strings.ToUpper(countryID)}]; ok {
+			return r, true
+		}
+	}
+	r, ok := d.records[recordKey{name: name}]
+	return r, ok
+}
+
+// merge lets several rows describe the same name and country, e.g. one row per
+// nationality candidate next to the row with age and sex.
+func merge(existing record, r record) record {
+	if r.Age != 0 {
+		existing.Age = r.Age
+	}
+	if r.Sex != "" {
+		existing.Sex = r.Sex
+		existing.SexProbability = r.SexProbability
+	}
+	existing.Count = max(existing.Count, r.Count)
+	return existing
+}
+
+func toSexEnum(s string) domain.SexEnum {
+	switch strings.ToUpper(s) {
+	case string(domain.MaleSexEnum):
+		return domain.MaleSexEnum
+	case string(domain.FemaleSexEnum):
+		return domain.FemaleSexEnum
+	}
+	return ""
+}
+
+func load(path string) ([]record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var records []record
+		if err := json.NewDecoder(f).Decode(&records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	case ".csv":
+		return loadCSV(f)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func loadCSV(r io.Reader) ([]record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("header has no name column")
+	}
+
+	var records []record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		r := record{
+			Name:        field("name"),
+			CountryID:   field("country_id"),
+			Sex:         field("sex"),
+			Nationality: field("nationality"),
+		}
+		if r.Age, err = parseOptional(field("age"), strconv.Atoi); err != nil {
+			return nil, fmt.Errorf("line %d: age: %w", line, err)
+		}
+		if r.SexProbability, err = parseOptional(field("sex_probability"), parseFloat); err != nil {
+			return nil, fmt.Errorf("line %d: sex_probability: %w", line, err)
+		}
+		if r.NationalityProbability, err = parseOptional(field("nationality_probability"), parseFloat); err != nil {
+			return nil, fmt.Errorf("line %d: nationality_probability: %w", line, err)
+		}
+		if r.Count, err = parseOptional(field("count"), parseInt); err != nil {
+			return nil, fmt.Errorf("line %d: count: %w", line, err)
+		}
+		records = append(records, r)
+	}
+}
+
+func parseOptional[T any](s string, parse func(string) (T, error)) (T, error) {
+	var zero T
+	if s == "" {
+		return zero, nil
+	}
+	return parse(s)
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseInt(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
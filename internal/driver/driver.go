@@ -1,12 +1,37 @@
 package driver
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/FlyKarlik/effectiveMobile/config"
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	cache_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/cache"
+	chain_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/chain"
+	dataset_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/dataset"
+	rules_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/rules"
+	singleflight_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/singleflight"
 	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
 )
 
+const (
+	providerRemote  = "remote"
+	providerCache   = "cache"
+	providerDataset = "dataset"
+)
+
+var (
+	ErrUserDriverRequired  = errors.New("user driver must be configured before its decorators")
+	ErrDatasetPathRequired = errors.New("dataset provider requires INFRA__ENRICHMENT__DATASET_PATH")
+	ErrUnknownProvider     = errors.New("unknown enrichment provider")
+)
+
 type Driver struct {
 	user_drver.IUserDriver
+	cache_drver.IEnrichmentCache
+	SexRules user_drver.IUserDriver
 }
 
 type driverOptions func(r *Driver) error
@@ -24,9 +49,83 @@ func New(cfgs ...driverOptions) (*Driver, error) {
 	return os, nil
 }
 
-func WithUserDriver(logger logger.Logger) driverOptions {
+func WithSexRules(logger logger.Logger) driverOptions {
+	return func(r *Driver) error {
+		r.SexRules = rules_drver.New(logger)
+		return nil
+	}
+}
+
+// WithProviderChains builds one provider chain per attribute from config.
+// Remote providers and the dataset are only set up when some chain uses them,
+// so a configuration without "remote" runs fully offline. A cache entry wraps
+// the rest of its chain and stores whatever the providers after it return.
+func WithProviderChains(logger logger.Logger, client *http.Client, store cache_drver.ICacheStore, cfg *config.Enrichment) driverOptions {
+	return func(r *Driver) error {
+		chains := map[domain.EnrichmentAttributeEnum][]string{
+			domain.AgeEnrichmentAttribute:         cfg.AgeProviders,
+			domain.SexEnrichmentAttribute:         cfg.SexProviders,
+			domain.NationalityEnrichmentAttribute: cfg.NationalityProviders,
+		}
+
+		cache := cache_drver.New(logger, store, cfg)
+		r.IEnrichmentCache = cache
+
+		providers := make(map[string]user_drver.IUserDriver)
+		for _, names := range chains {
+			for _, name := range names {
+				if _, ok := providers[name]; ok {
+					continue
+				}
+				switch name {
+				case providerRemote:
+					providers[name] = user_drver.New(logger, client, cfg)
+				case providerDataset:
+					if cfg.DatasetPath == "" {
+						return ErrDatasetPathRequired
+					}
+					dataset, err := dataset_drver.New(logger, cfg.DatasetPath)
+					if err != nil {
+						return err
+					}
+					providers[name] = dataset
+				case providerCache:
+				default:
+					return fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+				}
+			}
+		}
+
+		build := func(names []string) user_drver.IUserDriver {
+			var links []chain_drver.Link
+			for i := len(names) - 1; i >= 0; i-- {
+				if names[i] == providerCache {
+					rest := chain_drver.NewFallback(logger, links)
+					links = []chain_drver.Link{{Name: providerCache, Driver: cache.Wrap(rest)}}
+					continue
+				}
+				links = append([]chain_drver.Link{{Name: names[i], Driver: providers[names[i]]}}, links...)
+			}
+			return chain_drver.NewFallback(logger, links)
+		}
+
+		r.IUserDriver = chain_drver.NewRouter(
+			build(chains[domain.AgeEnrichmentAttribute]),
+			build(chains[domain.SexEnrichmentAttribute]),
+			build(chains[domain.NationalityEnrichmentAttribute]),
+			providers[providerRemote],
+		)
+		return nil
+	}
+}
+
+func WithSingleflight(logger logger.Logger) driverOptions {
 	return func(r *Driver) error {
-		r.IUserDriver = user_drver.New(logger)
+		if r.IUserDriver == nil {
+			return ErrUserDriverRequired
+		}
+
+		r.IUserDriver = singleflight_drver.New(logger, r.IUserDriver)
 		return nil
 	}
 }
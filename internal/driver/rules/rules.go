@@ -0,0 +1,137 @@
+package rules_drver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+)
+
+type suffixRule struct {
+	suffix string
+	sex    domain.SexEnum
+}
+
+// Patronymics are checked before surnames: they are grammatically gendered
+// with almost no exceptions, while surname endings only cover the common
+// Slavic patterns. Longer suffixes come first within each list.
+var patronymicRules = []suffixRule{
+	{suffix: "инична", sex: domain.FemaleSexEnum},
+	{suffix: "ична", sex: domain.FemaleSexEnum},
+	{suffix: "овна", sex: domain.FemaleSexEnum},
+	{suffix: "евна", sex: domain.FemaleSexEnum},
+	{suffix: "ович", sex: domain.MaleSexEnum},
+	{suffix: "евич", sex: domain.MaleSexEnum},
+	{suffix: "ич", sex: domain.MaleSexEnum},
+	{suffix: "қызы", sex: domain.FemaleSexEnum},
+	{suffix: "кызы", sex: domain.FemaleSexEnum},
+	{suffix: "ұлы", sex: domain.MaleSexEnum},
+	{suffix: "улы", sex: domain.MaleSexEnum},
+	{suffix: "оглы", sex: domain.MaleSexEnum},
+	{suffix: "ovna", sex: domain.FemaleSexEnum},
+	{suffix: "evna", sex: domain.FemaleSexEnum},
+	{suffix: "ichna", sex: domain.FemaleSexEnum},
+	{suffix: "ovich", sex: domain.MaleSexEnum},
+	{suffix: "evich", sex: domain.MaleSexEnum},
+	{suffix: "kyzy", sex: domain.FemaleSexEnum},
+	{suffix: "uly", sex: domain.MaleSexEnum},
+}
+
+var surnameRules = []suffixRule{
+	{suffix: "ская", sex: domain.FemaleSexEnum},
+	{suffix: "цкая", sex: domain.FemaleSexEnum},
+	{suffix: "ова", sex: domain.FemaleSexEnum},
+	{suffix: "ева", sex: domain.FemaleSexEnum},
+	{suffix: "ёва", sex: domain.FemaleSexEnum},
+	{suffix: "ина", sex: domain.FemaleSexEnum},
+	{suffix: "ына", sex: domain.FemaleSexEnum},
+	{suffix: "ский", sex: domain.MaleSexEnum},
+	{suffix: "цкий", sex: domain.MaleSexEnum},
+	{suffix: "ской", sex: domain.MaleSexEnum},
+	{suffix: "ов", sex: domain.MaleSexEnum},
+	{suffix: "ев", sex: domain.MaleSexEnum},
+	{suffix: "ёв", sex: domain.MaleSexEnum},
+	{suffix: "ин", sex: domain.MaleSexEnum},
+	{suffix: "ын", sex: domain.MaleSexEnum},
+	{suffix: "skaya", sex: domain.FemaleSexEnum},
+	{suffix: "skaia", sex: domain.FemaleSexEnum},
+	{suffix: "ova", sex: domain.FemaleSexEnum},
+	{suffix: "eva", sex: domain.FemaleSexEnum},
+	{suffix: "skiy", sex: domain.MaleSexEnum},
+	{suffix: "skii", sex: domain.MaleSexEnum},
+	{suffix: "sky", sex: domain.MaleSexEnum},
+	{suffix: "ov", sex: domain.MaleSexEnum},
+	{suffix: "ev", sex: domain.MaleSexEnum},
+}
+
+// rulesUserDriver infers sex offline from Russian and Kazakh patronymic and
+// surname endings. It has no data for age or nationality and answers those
+// with empty predictions, the same way a provider answers for unknown names.
+type rulesUserDriver struct {
+	logger logger.Logger
+}
+
+func New(logger logger.Logger) *rulesUserDriver {
+	return &rulesUserDriver{
+		logger: logger,
+	}
+}
+
+func (r *rulesUserDriver) GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error) {
+	return domain.AgePrediction{}, nil
+}
+
+func (r *rulesUserDriver) GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error) {
+	return domain.NationalityPrediction{}, nil
+}
+
+func (r *rulesUserDriver) GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error) {
+	const method = "GetUserSex"
+	const layer = "driver"
+	r.logger.Debug(layer, method, "started", "surname", query.Surname, "patronymic", query.Patronymic)
+
+	sex := matchSuffix(patronymicRules, query.Patronymic)
+	if sex == "" {
+		sex = matchSuffix(surnameRules, query.Surname)
+	}
+
+	if sex == "" {
+		r.logger.Debug(layer, method, "no rule matched", "surname", query.Surname, "patronymic", query.Patronymic)
+		return domain.SexPrediction{}, nil
+	}
+
+	r.logger.Debug(layer, method, "successfully completed", "sex", sex)
+	return domain.SexPrediction{Sex: sex}, nil
+}
+
+// The batch methods only receive first names, which the rules cannot use.
+func (r *rulesUserDriver) GetUsersAgeBatch(ctx context.Context, names []string, countryID string) (map[string]domain.AgePrediction, error) {
+	return map[string]domain.AgePrediction{}, nil
+}
+
+func (r *rulesUserDriver) GetUsersNationalityBatch(ctx context.Context, names []string) (map[string]domain.NationalityPrediction, error) {
+	return map[string]domain.NationalityPrediction{}, nil
+}
+
+func (r *rulesUserDriver) GetUsersSexBatch(ctx context.Context, names []string, countryID string) (map[string]domain.SexPrediction, error) {
+	return map[string]domain.SexPrediction{}, nil
+}
+
+func (r *rulesUserDriver) GetProvidersState(ctx context.Context) []domain.ProviderState {
+	return nil
+}
+
+func matchSuffix(rules []suffixRule, value string) domain.SexEnum {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+
+	for _, rule := range rules {
+		if strings.HasSuffix(value, rule.suffix) && len(value) > len(rule.suffix) {
+			return rule.sex
+		}
+	}
+	return ""
+}
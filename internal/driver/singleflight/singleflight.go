@@ -0,0 +1,123 @@
+package singleflight_drver
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+	"golang.org/x/sync/singleflight"
+)
+
+type singleflightUserDriver struct {
+	user_drver.IUserDriver
+	logger logger.Logger
+	group  singleflight.Group
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is the context shared by the callers waiting on one key.
+type flight struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+func New(logger logger.Logger, next user_drver.IUserDriver) *singleflightUserDriver {
+	return &singleflightUserDriver{
+		IUserDriver: next,
+		logger:      logger,
+		flights:     make(map[string]*flight),
+	}
+}
+
+func (s *singleflightUserDriver) GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error) {
+	key := newKey(domain.AgeEnrichmentAttribute, query)
+	return do(ctx, s, "GetUserAge", key, func(ctx context.Context) (domain.AgePrediction, error) {
+		return s.IUserDriver.GetUserAge(ctx, query)
+	})
+}
+
+func (s *singleflightUserDriver) GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error) {
+	key := newKey(domain.NationalityEnrichmentAttribute, domain.EnrichmentQuery{Name: name})
+	return do(ctx, s, "GetUserNationality", key, func(ctx context.Context) (domain.NationalityPrediction, error) {
+		return s.IUserDriver.GetUserNationality(ctx, name)
+	})
+}
+
+func (s *singleflightUserDriver) GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error) {
+	key := newKey(domain.SexEnrichmentAttribute, query)
+	return do(ctx, s, "GetUserSex", key, func(ctx context.Context) (domain.SexPrediction, error) {
+		return s.IUserDriver.GetUserSex(ctx, query)
+	})
+}
+
+// do runs fetch once per key for all concurrent callers. The shared call is
+// detached from the first caller's context so that one client giving up does
+// not fail everybody else, and is cancelled once the last caller has stopped
+// waiting, so it never outlives all of them. Each caller still stops waiting
+// on its own context.
+func do[T any](ctx context.Context, s *singleflightUserDriver, method string, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	const layer = "driver"
+
+	f := s.join(ctx, key)
+	defer s.leave(key, f)
+
+	ch := s.group.DoChan(key, func() (interface{}, error) {
+		return fetch(f.ctx)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		s.logger.Debug(layer, method, "caller stopped waiting for shared lookup", "key", key)
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			s.logger.Debug(layer, method, "shared in-flight lookup", "key", key)
+		}
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
+
+func (s *singleflightUserDriver) join(ctx context.Context, key string) *flight {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.flights[key]
+	if !ok {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{ctx: flightCtx, cancel: cancel}
+		s.flights[key] = f
+	}
+	f.waiters++
+	return f
+}
+
+// leave cancels the shared call once nobody waits for it. The key is
+// forgotten too, so a caller coming later starts a fresh lookup instead of
+// joining the cancelled one.
+func (s *singleflightUserDriver) leave(key string, f *flight) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+
+	f.cancel()
+	delete(s.flights, key)
+	s.group.Forget(key)
+}
+
+func newKey(attribute domain.EnrichmentAttributeEnum, query domain.EnrichmentQuery) string {
+	return string(attribute) + ":" + strings.ToUpper(query.CountryID) + ":" + user_drver.NormalizeName(query.Name)
+}
@@ -0,0 +1,209 @@
+package user_drver
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+	"golang.org/x/sync/errgroup"
+)
+
+// batchSize is the maximum number of name[] parameters the providers accept
+// in one request.
+const batchSize = 10
+
+// prefetchConcurrency bounds the batch lookups Prefetch runs at once.
+const prefetchConcurrency = 5
+
+// Prefetch looks the queries up through the batch methods of d, so that the
+// single lookups made for them afterwards are answered by the cache in front
+// of the providers. Queries without a country are localized to the
+// nationality predicted for their name, as single lookups are, which makes
+// both ask for the same cache keys. Failed batches are only logged; it
+// reports for every query whether all three attributes were found.
+func Prefetch(ctx context.Context, logger logger.Logger, d IUserDriver, queries []domain.EnrichmentQuery) []bool {
+	const layer = "driver"
+	const method = "Prefetch"
+
+	logger.Debug(layer, method, "started", "queries", len(queries))
+
+	names := make([]string, 0, len(queries))
+	for _, query := range queries {
+		names = append(names, query.Name)
+	}
+
+	nationalities, err := d.GetUsersNationalityBatch(ctx, names)
+	if err != nil {
+		logger.Warn(layer, method, "failed to prefetch some nationalities", err)
+	}
+
+	countries := make([]string, len(queries))
+	byCountry := make(map[string][]string)
+	for i, query := range queries {
+		countries[i] = query.CountryID
+		if countries[i] == "" {
+			countries[i] = nationalities[query.Name].CountryID
+		}
+		byCountry[countries[i]] = append(byCountry[countries[i]], query.Name)
+	}
+
+	var (
+		mu    sync.Mutex
+		ages  = make(map[string]map[string]domain.AgePrediction, len(byCountry))
+		sexes = make(map[string]map[string]domain.SexPrediction, len(byCountry))
+	)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(prefetchConcurrency)
+
+	for country, countryNames := range byCountry {
+		g.Go(func() error {
+			predictions, err := d.GetUsersAgeBatch(gctx, countryNames, country)
+			if err != nil {
+				logger.Warn(layer, method, "failed to prefetch some ages", err, "country_id", country)
+			}
+			mu.Lock()
+			ages[country] = predictions
+			mu.Unlock()
+			return nil
+		})
+		g.Go(func() error {
+			predictions, err := d.GetUsersSexBatch(gctx, countryNames, country)
+			if err != nil {
+				logger.Warn(layer, method, "failed to prefetch some sexes", err, "country_id", country)
+			}
+			mu.Lock()
+			sexes[country] = predictions
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	// The goroutines only log their errors.
+	_ = g.Wait()
+
+	complete := make([]bool, len(queries))
+	found := 0
+	for i, query := range queries {
+		_, hasNationality := nationalities[query.Name]
+		_, hasAge := ages[countries[i]][query.Name]
+		_, hasSex := sexes[countries[i]][query.Name]
+		complete[i] = hasNationality && hasAge && hasSex
+		if complete[i] {
+			found++
+		}
+	}
+
+	logger.Debug(layer, method, "successfully completed", "queries", len(queries), "complete", found)
+	return complete
+}
+
+type namedResponse interface {
+	agifyResponse | genderizeResponse | nationalizeResponse
+}
+
+func (u *userDriver) GetUsersAgeBatch(ctx context.Context, names []string, countryID string) (map[string]domain.AgePrediction, error) {
+	const method = "GetUsersAgeBatch"
+	const layer = "driver"
+	u.logger.Debug(layer, method, "started", "names", len(names), "country_id", countryID)
+
+	predictions, err := getBatch(ctx, u, method, u.agify, names, countryID, func(r agifyResponse) (string, domain.AgePrediction) {
+		return r.Name, r.toDomain()
+	})
+
+	if err != nil {
+		u.logger.Warn(layer, method, "some batches failed", err, "names", len(names), "predictions", len(predictions))
+		return predictions, err
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "names", len(names), "predictions", len(predictions))
+	return predictions, nil
+}
+
+func (u *userDriver) GetUsersNationalityBatch(ctx context.Context, names []string) (map[string]domain.NationalityPrediction, error) {
+	const method = "GetUsersNationalityBatch"
+	const layer = "driver"
+	u.logger.Debug(layer, method, "started", "names", len(names))
+
+	predictions, err := getBatch(ctx, u, method, u.nationalize, names, "", func(r nationalizeResponse) (string, domain.NationalityPrediction) {
+		return r.Name, r.toDomain()
+	})
+
+	if err != nil {
+		u.logger.Warn(layer, method, "some batches failed", err, "names", len(names), "predictions", len(predictions))
+		return predictions, err
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "names", len(names), "predictions", len(predictions))
+	return predictions, nil
+}
+
+func (u *userDriver) GetUsersSexBatch(ctx context.Context, names []string, countryID string) (map[string]domain.SexPrediction, error) {
+	const method = "GetUsersSexBatch"
+	const layer = "driver"
+	u.logger.Debug(layer, method, "started", "names", len(names), "country_id", countryID)
+
+	predictions, err := getBatch(ctx, u, method, u.genderize, names, countryID, func(r genderizeResponse) (string, domain.SexPrediction) {
+		return r.Name, r.toDomain()
+	})
+
+	if err != nil {
+		u.logger.Warn(layer, method, "some batches failed", err, "names", len(names), "predictions", len(predictions))
+		return predictions, err
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "names", len(names), "predictions", len(predictions))
+	return predictions, nil
+}
+
+// getBatch splits names into provider-sized chunks and maps every answer back
+// to the names the caller passed in. Providers echo the name they were asked
+// about, so answers are matched on the normalized form rather than position.
+// A failed chunk does not discard the others: the predictions that were
+// fetched are returned together with the joined chunk errors.
+func getBatch[R namedResponse, T any](ctx context.Context, u *userDriver, method string, p *provider, names []string, countryID string, convert func(R) (string, T)) (map[string]T, error) {
+	const layer = "driver"
+
+	requested := make(map[string][]string, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized := NormalizeName(name)
+		if _, ok := requested[normalized]; !ok {
+			unique = append(unique, name)
+		}
+		requested[normalized] = append(requested[normalized], name)
+	}
+
+	predictions := make(map[string]T, len(names))
+	var errList []error
+
+	for start := 0; start < len(unique); start += batchSize {
+		chunk := unique[start:min(start+batchSize, len(unique))]
+
+		var responses []R
+		var err error
+		if len(chunk) == 1 {
+			var single R
+			err = u.getJSON(ctx, method, p, chunk, countryID, &single)
+			responses = []R{single}
+		} else {
+			err = u.getJSON(ctx, method, p, chunk, countryID, &responses)
+		}
+		if err != nil {
+			u.logger.Warn(layer, method, "batch request failed", err, "provider", p.name, "names", chunk)
+			errList = append(errList, err)
+			continue
+		}
+
+		for _, response := range responses {
+			name, prediction := convert(response)
+			for _, original := range requested[NormalizeName(name)] {
+				predictions[original] = prediction
+			}
+		}
+	}
+
+	return predictions, errors.Join(errList...)
+}
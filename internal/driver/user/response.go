@@ -0,0 +1,58 @@
+package user_drver
+
+import "github.com/FlyKarlik/effectiveMobile/internal/domain"
+
+type agifyResponse struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+	Age   int    `json:"age"`
+}
+
+func (r agifyResponse) toDomain() domain.AgePrediction {
+	return domain.AgePrediction{
+		Age:   r.Age,
+		Count: r.Count,
+	}
+}
+
+type genderizeResponse struct {
+	Name        string  `json:"name"`
+	Count       int64   `json:"count"`
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+}
+
+func (r genderizeResponse) toDomain() domain.SexPrediction {
+	return domain.SexPrediction{
+		Sex:         getSexEnum(r.Gender),
+		Probability: r.Probability,
+		Count:       r.Count,
+	}
+}
+
+type nationalizeResponse struct {
+	Name    string `json:"name"`
+	Count   int64  `json:"count"`
+	Country []struct {
+		CountryID   string  `json:"country_id"`
+		Probability float64 `json:"probability"`
+	} `json:"country"`
+}
+
+func (r nationalizeResponse) toDomain() domain.NationalityPrediction {
+	prediction := domain.NationalityPrediction{Count: r.Count}
+	if len(r.Country) == 0 {
+		return prediction
+	}
+
+	prediction.CountryID = r.Country[0].CountryID
+	prediction.Probability = r.Country[0].Probability
+	prediction.Candidates = make([]domain.NationalityCandidate, 0, len(r.Country))
+	for _, country := range r.Country {
+		prediction.Candidates = append(prediction.Candidates, domain.NationalityCandidate{
+			CountryID:   country.CountryID,
+			Probability: country.Probability,
+		})
+	}
+	return prediction
+}
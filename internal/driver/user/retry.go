@@ -0,0 +1,123 @@
+package user_drver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitRemaining = "X-Rate-Limit-Remaining"
+	headerRateLimitReset     = "X-Rate-Limit-Reset"
+)
+
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+type providerStats struct {
+	requests    atomic.Int64
+	retries     atomic.Int64
+	rateLimited atomic.Int64
+	failures    atomic.Int64
+	rejected    atomic.Int64
+}
+
+type statusError struct {
+	statusCode int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.statusCode)
+}
+
+func newStatusError(resp *http.Response) *statusError {
+	return &statusError{
+		statusCode: resp.StatusCode,
+		retryAfter: parseRetryAfter(resp.StatusCode, resp.Header, time.Now()),
+	}
+}
+
+func (p retryPolicy) isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return isProviderFailure(err)
+}
+
+// isProviderFailure reports whether err points at the provider rather than at
+// the request: a network error, a 5xx or a 429. A bad API key or a name the
+// provider rejects is answered with another 4xx, which a retry would not fix.
+func isProviderFailure(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode == http.StatusTooManyRequests || statusErr.statusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// delay returns how long to wait before the next attempt and false when the
+// provider asked us to wait longer than the policy allows.
+func (p retryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	backoff := p.baseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.maxDelay {
+		backoff = p.maxDelay
+	}
+	backoff = backoff/2 + rand.N(backoff/2+1)
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.retryAfter > backoff {
+		if statusErr.retryAfter > p.maxDelay {
+			return 0, false
+		}
+		return statusErr.retryAfter, true
+	}
+
+	return backoff, true
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter returns how long the provider asked us to wait. The
+// providers send X-Rate-Limit-Reset on every response, counting down to the
+// daily quota reset, so the headers are only taken as a wait on a 429 or once
+// the quota is used up. Any other failure backs off as usual.
+func parseRetryAfter(statusCode int, header http.Header, now time.Time) time.Duration {
+	if statusCode != http.StatusTooManyRequests && header.Get(headerRateLimitRemaining) != "0" {
+		return 0
+	}
+
+	if value := header.Get(headerRetryAfter); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0)
+		}
+	}
+
+	if seconds, err := strconv.Atoi(header.Get(headerRateLimitReset)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	return 0
+}
@@ -0,0 +1,127 @@
+package user_drver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		statusCode int
+		header     map[string]string
+		want       time.Duration
+	}{
+		{
+			name:       "503 with quota reset",
+			statusCode: http.StatusServiceUnavailable,
+			header:     map[string]string{headerRateLimitRemaining: "812", headerRateLimitReset: "43200"},
+			want:       0,
+		},
+		{
+			name:       "503 with retry after",
+			statusCode: http.StatusServiceUnavailable,
+			header:     map[string]string{headerRetryAfter: "2"},
+			want:       0,
+		},
+		{
+			name:       "503 with quota used up",
+			statusCode: http.StatusServiceUnavailable,
+			header:     map[string]string{headerRateLimitRemaining: "0", headerRateLimitReset: "3"},
+			want:       3 * time.Second,
+		},
+		{
+			name:       "429 with retry after seconds",
+			statusCode: http.StatusTooManyRequests,
+			header:     map[string]string{headerRetryAfter: "2", headerRateLimitReset: "43200"},
+			want:       2 * time.Second,
+		},
+		{
+			name:       "429 with retry after date",
+			statusCode: http.StatusTooManyRequests,
+			header:     map[string]string{headerRetryAfter: now.Add(5 * time.Second).Format(http.TimeFormat)},
+			want:       5 * time.Second,
+		},
+		{
+			name:       "429 with quota reset",
+			statusCode: http.StatusTooManyRequests,
+			header:     map[string]string{headerRateLimitRemaining: "0", headerRateLimitReset: "43200"},
+			want:       12 * time.Hour,
+		},
+		{
+			name:       "429 without headers",
+			statusCode: http.StatusTooManyRequests,
+			want:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.header {
+				header.Set(key, value)
+			}
+
+			if got := parseRetryAfter(tt.statusCode, header, now); got != tt.want {
+				t.Errorf("parseRetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 3, baseDelay: 100 * time.Millisecond, maxDelay: 5 * time.Second}
+
+	tests := []struct {
+		name       string
+		statusCode int
+		header     map[string]string
+		wantMin    time.Duration
+		wantMax    time.Duration
+		wantOK     bool
+	}{
+		{
+			name:       "503 with quota reset backs off",
+			statusCode: http.StatusServiceUnavailable,
+			header:     map[string]string{headerRateLimitRemaining: "812", headerRateLimitReset: "43200"},
+			wantMin:    50 * time.Millisecond,
+			wantMax:    100 * time.Millisecond,
+			wantOK:     true,
+		},
+		{
+			name:       "429 with retry after waits as asked",
+			statusCode: http.StatusTooManyRequests,
+			header:     map[string]string{headerRetryAfter: "2"},
+			wantMin:    2 * time.Second,
+			wantMax:    2 * time.Second,
+			wantOK:     true,
+		},
+		{
+			name:       "429 with reset above max delay gives up",
+			statusCode: http.StatusTooManyRequests,
+			header:     map[string]string{headerRateLimitRemaining: "0", headerRateLimitReset: "43200"},
+			wantOK:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.header {
+				header.Set(key, value)
+			}
+			err := newStatusError(&http.Response{StatusCode: tt.statusCode, Header: header})
+
+			got, ok := policy.delay(1, err)
+			if ok != tt.wantOK {
+				t.Fatalf("delay() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (got < tt.wantMin || got > tt.wantMax) {
+				t.Errorf("delay() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
@@ -3,181 +3,320 @@ package user_drver
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/FlyKarlik/effectiveMobile/config"
 	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/pkg/breaker"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
 )
 
 type IUserDriver interface {
-	GetUserAge(ctx context.Context, name string) (int, error)
-	GetUserNationality(ctx context.Context, name string) (string, error)
-	GetUserSex(ctx context.Context, name string) (domain.SexEnum, error)
+	GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error)
+	GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error)
+	GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error)
+	GetUsersAgeBatch(ctx context.Context, names []string, countryID string) (map[string]domain.AgePrediction, error)
+	GetUsersNationalityBatch(ctx context.Context, names []string) (map[string]domain.NationalityPrediction, error)
+	GetUsersSexBatch(ctx context.Context, names []string, countryID string) (map[string]domain.SexPrediction, error)
+	GetProvidersState(ctx context.Context) []domain.ProviderState
+}
+
+type provider struct {
+	name    string
+	baseURL string
+	apiKey  string
+	stats   providerStats
+	breaker *breaker.Breaker
 }
 
 type userDriver struct {
-	logger logger.Logger
+	logger      logger.Logger
+	client      *http.Client
+	retry       retryPolicy
+	agify       *provider
+	genderize   *provider
+	nationalize *provider
 }
 
-func New(logger logger.Logger) *userDriver {
+func New(logger logger.Logger, client *http.Client, cfg *config.Enrichment) *userDriver {
+	breakerSettings := breaker.Settings{
+		FailureThreshold:    cfg.BreakerFailureThreshold,
+		OpenTimeout:         cfg.BreakerOpenTimeout,
+		HalfOpenMaxRequests: cfg.BreakerHalfOpenMaxRequests,
+	}
+
 	return &userDriver{
 		logger: logger,
+		client: client,
+		retry: retryPolicy{
+			maxAttempts: cfg.RetryMaxAttempts,
+			baseDelay:   cfg.RetryBaseDelay,
+			maxDelay:    cfg.RetryMaxDelay,
+		},
+		agify: &provider{
+			name:    "agify",
+			baseURL: cfg.AgifyURL,
+			apiKey:  cfg.AgifyAPIKey,
+			breaker: breaker.New("agify", breakerSettings),
+		},
+		genderize: &provider{
+			name:    "genderize",
+			baseURL: cfg.GenderizeURL,
+			apiKey:  cfg.GenderizeAPIKey,
+			breaker: breaker.New("genderize", breakerSettings),
+		},
+		nationalize: &provider{
+			name:    "nationalize",
+			baseURL: cfg.NationalizeURL,
+			apiKey:  cfg.NationalizeAPIKey,
+			breaker: breaker.New("nationalize", breakerSettings),
+		},
 	}
 }
 
-func (u *userDriver) GetUserAge(ctx context.Context, name string) (int, error) {
+func (u *userDriver) GetUserAge(ctx context.Context, query domain.EnrichmentQuery) (domain.AgePrediction, error) {
 	const method = "GetUserAge"
 	const layer = "driver"
-	u.logger.Debug(layer, method, "started", "name", name)
+	u.logger.Debug(layer, method, "started", "name", query.Name, "country_id", query.CountryID)
 
-	var userAge struct {
-		Age int `json:"age"`
+	var userAge agifyResponse
+	if err := u.getJSON(ctx, method, u.agify, []string{query.Name}, query.CountryID, &userAge); err != nil {
+		return domain.AgePrediction{}, err
 	}
 
-	URL := fmt.Sprintf("https://api.agify.io/?name=%s", name)
-	u.logger.Debug(layer, method, "making request", "url", URL)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
-	if err != nil {
-		u.logger.Error(layer, method, "request failed", err, "url", URL)
-		return 0, err
-	}
+	prediction := userAge.toDomain()
+	u.logger.Debug(layer, method, "successfully completed", "name", query.Name, "prediction", prediction)
+	return prediction, nil
+}
 
-	client := &http.Client{}
+func (u *userDriver) GetUserNationality(ctx context.Context, name string) (domain.NationalityPrediction, error) {
+	const method = "GetUserNationality"
+	const layer = "driver"
+	u.logger.Debug(layer, method, "started", "name", name)
 
-	resp, err := client.Do(req)
-	if err != nil {
-		u.logger.Error(layer, method, "failed to client do request", err)
-		return 0, err
+	// nationalize has no country parameter: the country is what it predicts.
+	var userNationality nationalizeResponse
+	if err := u.getJSON(ctx, method, u.nationalize, []string{name}, "", &userNationality); err != nil {
+		return domain.NationalityPrediction{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-		u.logger.Error(layer, method, "bad response status", err, "status", resp.StatusCode)
-		return 0, err
+	prediction := userNationality.toDomain()
+	if prediction.CountryID == "" {
+		u.logger.Warn(layer, method, "no country found", nil, "name", name)
+		return prediction, nil
 	}
+	u.logger.Debug(layer, method, "successfully completed", "name", name, "prediction", prediction)
+	return prediction, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		u.logger.Error(layer, method, "failed to read response body", err)
-		return 0, err
-	}
+func (u *userDriver) GetUserSex(ctx context.Context, query domain.EnrichmentQuery) (domain.SexPrediction, error) {
+	const method = "GetUserSex"
+	const layer = "driver"
+	u.logger.Debug(layer, method, "started", "name", query.Name, "country_id", query.CountryID)
 
-	if err := json.Unmarshal(body, &userAge); err != nil {
-		u.logger.Error(layer, method, "failed to unmarshal response", err, "body", string(body))
-		return 0, err
+	var userGender genderizeResponse
+	if err := u.getJSON(ctx, method, u.genderize, []string{query.Name}, query.CountryID, &userGender); err != nil {
+		return domain.SexPrediction{}, err
 	}
 
-	u.logger.Debug(layer, method, "successfully completed", "name", name, "age", userAge.Age)
-	return userAge.Age, nil
+	prediction := userGender.toDomain()
+	u.logger.Debug(layer, method, "successfully completed", "name", query.Name, "prediction", prediction)
+	return prediction, nil
 }
 
-func (u *userDriver) GetUserNationality(ctx context.Context, name string) (string, error) {
-	const method = "GetUserNationality"
+func (u *userDriver) GetProvidersState(ctx context.Context) []domain.ProviderState {
+	const method = "GetProvidersState"
 	const layer = "driver"
-	u.logger.Debug(layer, method, "started", "name", name)
-
-	var userNationality struct {
-		Country []struct {
-			CountryID string `json:"country_id"`
-		} `json:"country"`
+	u.logger.Debug(layer, method, "started")
+
+	providers := []*provider{u.agify, u.genderize, u.nationalize}
+	states := make([]domain.ProviderState, 0, len(providers))
+	for _, p := range providers {
+		snapshot := p.breaker.Snapshot()
+
+		state := domain.ProviderState{
+			Provider:            p.name,
+			BreakerState:        snapshot.State.String(),
+			ConsecutiveFailures: snapshot.ConsecutiveFailures,
+			Requests:            p.stats.requests.Load(),
+			Retries:             p.stats.retries.Load(),
+			RateLimited:         p.stats.rateLimited.Load(),
+			Failures:            p.stats.failures.Load(),
+			Rejected:            p.stats.rejected.Load(),
+		}
+		if !snapshot.OpenedAt.IsZero() {
+			openedAt := snapshot.OpenedAt
+			state.OpenedAt = &openedAt
+		}
+		states = append(states, state)
 	}
 
-	URL := fmt.Sprintf("https://api.nationalize.io/?name=%s", name)
-	u.logger.Debug(layer, method, "making request", "url", URL)
+	u.logger.Debug(layer, method, "successfully completed", "providers", len(states))
+	return states
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+func (u *userDriver) getJSON(ctx context.Context, method string, p *provider, names []string, countryID string, dst interface{}) error {
+	const layer = "driver"
+
+	URL, err := p.buildURL(names, countryID)
 	if err != nil {
-		u.logger.Error(layer, method, "request failed", err, "url", URL)
-		return "", err
+		u.logger.Error(layer, method, "failed to build url", err, "provider", p.name)
+		return err
 	}
 
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		u.logger.Error(layer, method, "failed to client do request", err)
-		return "", err
+	if err := p.breaker.Allow(); err != nil {
+		p.stats.rejected.Add(1)
+		u.logger.Warn(layer, method, "circuit breaker rejected request", err, "provider", p.name)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-		u.logger.Error(layer, method, "bad response status", err, "status", resp.StatusCode)
-		return "", err
+	// A request the provider turned down still shows it is up, so only
+	// failures on its side count towards opening the breaker.
+	body, err := u.doWithRetry(ctx, method, p, strings.Join(names, ","), URL)
+	switch {
+	case err == nil:
+		p.breaker.Success()
+	case ctx.Err() != nil:
+		p.breaker.Release()
+	case isProviderFailure(err):
+		p.breaker.Failure()
+	default:
+		p.breaker.Success()
 	}
+	u.logStats(method, p)
 
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		u.logger.Error(layer, method, "failed to read response body", err)
-		return "", err
+		return err
 	}
 
-	if err := json.Unmarshal(body, &userNationality); err != nil {
+	if err := json.Unmarshal(body, dst); err != nil {
 		u.logger.Error(layer, method, "failed to unmarshal response", err, "body", string(body))
-		return "", err
+		return err
 	}
 
-	if len(userNationality.Country) == 0 {
-		u.logger.Warn(layer, method, "no country found", nil, "name", name)
-		return "", nil
-	}
-
-	nationality := userNationality.Country[0].CountryID
-	u.logger.Debug(layer, method, "successfully completed", "name", name, "nationality", nationality)
-	return nationality, nil
+	return nil
 }
 
-func (u *userDriver) GetUserSex(ctx context.Context, name string) (domain.SexEnum, error) {
-	const method = "GetUserSex"
+func (u *userDriver) doWithRetry(ctx context.Context, method string, p *provider, name string, URL string) ([]byte, error) {
 	const layer = "driver"
-	u.logger.Debug(layer, method, "started", "name", name)
 
-	var userGender struct {
-		Gender string `json:"gender"`
+	for attempt := 1; ; attempt++ {
+		u.logger.Debug(layer, method, "making request", "provider", p.name, "name", name, "attempt", attempt)
+
+		body, err := u.doRequest(ctx, method, p, URL)
+		if err == nil {
+			return body, nil
+		}
+
+		if attempt >= u.retry.maxAttempts || !u.retry.isRetryable(ctx, err) {
+			p.stats.failures.Add(1)
+			return nil, err
+		}
+
+		delay, ok := u.retry.delay(attempt, err)
+		if !ok {
+			p.stats.failures.Add(1)
+			u.logger.Warn(layer, method, "provider asked to wait longer than allowed, giving up", err, "provider", p.name)
+			return nil, err
+		}
+
+		p.stats.retries.Add(1)
+		u.logger.Warn(layer, method, "retrying request", err, "provider", p.name, "attempt", attempt, "delay", delay.String())
+
+		if err := sleepContext(ctx, delay); err != nil {
+			p.stats.failures.Add(1)
+			return nil, err
+		}
 	}
+}
 
-	URL := fmt.Sprintf("https://api.genderize.io/?name=%s", name)
-	u.logger.Debug(layer, method, "making request", "url", URL)
+func (u *userDriver) doRequest(ctx context.Context, method string, p *provider, URL string) ([]byte, error) {
+	const layer = "driver"
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
 	if err != nil {
-		u.logger.Error("driver", method, "request failed", err, "url", URL)
-		return "", err
+		u.logger.Error(layer, method, "request failed", err, "provider", p.name)
+		return nil, err
 	}
 
-	client := &http.Client{}
+	p.stats.requests.Add(1)
 
-	resp, err := client.Do(req)
+	resp, err := u.client.Do(req)
 	if err != nil {
-		u.logger.Error(layer, method, "failed to client do request", err)
-		return "", err
+		u.logger.Error(layer, method, "failed to client do request", err, "provider", p.name)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-		u.logger.Error(layer, method, "bad response status", err, "status", resp.StatusCode)
-		return "", err
+		err := newStatusError(resp)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			p.stats.rateLimited.Add(1)
+		}
+		u.logger.Error(layer, method, "bad response status", err, "provider", p.name, "status", resp.StatusCode,
+			"rate_limit_remaining", resp.Header.Get(headerRateLimitRemaining),
+			"rate_limit_reset", resp.Header.Get(headerRateLimitReset))
+		return nil, err
+	}
+
+	if resp.Header.Get(headerRateLimitRemaining) == "0" {
+		u.logger.Warn(layer, method, "provider rate limit exhausted", nil, "provider", p.name,
+			"rate_limit_reset", resp.Header.Get(headerRateLimitReset))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		u.logger.Error(layer, method, "failed to read response body", err)
-		return "", err
+		u.logger.Error(layer, method, "failed to read response body", err, "provider", p.name)
+		return nil, err
 	}
 
-	if err := json.Unmarshal(body, &userGender); err != nil {
-		u.logger.Error(layer, method, "failed to unmarshal response", err, "body", string(body))
+	return body, nil
+}
+
+func (u *userDriver) logStats(method string, p *provider) {
+	const layer = "driver"
+	u.logger.Debug(layer, method, "provider stats",
+		"provider", p.name,
+		"requests", p.stats.requests.Load(),
+		"retries", p.stats.retries.Load(),
+		"rate_limited", p.stats.rateLimited.Load(),
+		"failures", p.stats.failures.Load(),
+		"rejected", p.stats.rejected.Load(),
+		"breaker_state", p.breaker.Snapshot().State.String())
+}
+
+// buildURL uses the plain name parameter for a single name and the name[]
+// form for batches, which makes the provider answer with a JSON array.
+func (p *provider) buildURL(names []string, countryID string) (string, error) {
+	u, err := url.Parse(p.baseURL)
+	if err != nil {
 		return "", err
 	}
 
-	sex := getSexEnum(userGender.Gender)
-	u.logger.Debug(layer, method, "successfully completed", "name", name, "sex", sex)
-	return sex, nil
+	values := u.Query()
+	if len(names) == 1 {
+		values.Set("name", names[0])
+	} else {
+		values["name[]"] = names
+	}
+	if countryID != "" {
+		values.Set("country_id", countryID)
+	}
+	if p.apiKey != "" {
+		values.Set("apikey", p.apiKey)
+	}
+	u.RawQuery = values.Encode()
+
+	return u.String(), nil
+}
+
+// NormalizeName folds case and whitespace so that lookups for the same name
+// share cache entries and in-flight requests.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
 }
 
 func getSexEnum(s string) domain.SexEnum {
@@ -1,18 +1,40 @@
 package errs
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
 type CustomError struct {
-	Code    ErrorCodeEnum
-	Message string
+	Code       ErrorCodeEnum `json:"code"`
+	Message    string        `json:"message"`
+	Violations []Violation   `json:"violations,omitempty"`
+}
+
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 func (c *CustomError) Error() string {
 	return fmt.Sprintf("code: %d, message: %s", c.Code, c.Message)
 }
 
+func (c *CustomError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code       ErrorCodeEnum `json:"code"`
+		Name       string        `json:"name"`
+		Message    string        `json:"message"`
+		Violations []Violation   `json:"violations,omitempty"`
+	}{
+		Code:       c.Code,
+		Name:       c.Code.String(),
+		Message:    c.Message,
+		Violations: c.Violations,
+	})
+}
+
 func New(code ErrorCodeEnum, msg string) *CustomError {
 	return &CustomError{
 		Code:    code,
@@ -20,6 +42,28 @@ func New(code ErrorCodeEnum, msg string) *CustomError {
 	}
 }
 
+// NewInvalidParamError reports a query parameter whose value could not be
+// parsed.
+func NewInvalidParamError(param string, message string) *CustomError {
+	return &CustomError{
+		Code:    CodeInvalidRequest,
+		Message: fmt.Sprintf("invalid query parameter %s: %s", param, message),
+		Violations: []Violation{{
+			Field:   param,
+			Rule:    "format",
+			Message: message,
+		}},
+	}
+}
+
+func NewValidationError(violations []Violation) *CustomError {
+	return &CustomError{
+		Code:       CodeValidationFailed,
+		Message:    "request validation failed",
+		Violations: violations,
+	}
+}
+
 type ErrorCodeEnum int
 
 const (
@@ -27,11 +71,58 @@ const (
 	CodeUserNotFound
 	CodeInvalidRequest
 	CodeParamRequired
+	CodeInvalidUserID
+	CodeConflict
+	CodeUnprocessable
+	CodeValidationFailed
+	CodeCacheEntryNotFound
+	CodeEnrichmentJobNotFound
+	CodeInvalidJobID
+	CodeInvalidSort
+	CodeInvalidCursor
+	CodeEnrichmentInProgress
 )
 
+var codeNames = map[ErrorCodeEnum]string{
+	CodeUnknown:            "unknown",
+	CodeUserNotFound:       "user-not-found",
+	CodeInvalidRequest:     "invalid-request",
+	CodeParamRequired:      "param-required",
+	CodeInvalidUserID:      "invalid-user-id",
+	CodeConflict:           "conflict",
+	CodeUnprocessable:      "unprocessable",
+	CodeValidationFailed:   "validation-failed",
+	CodeCacheEntryNotFound: "cache-entry-not-found",
+
+	CodeEnrichmentJobNotFound: "enrichment-job-not-found",
+	CodeInvalidJobID:          "invalid-job-id",
+	CodeInvalidSort:           "invalid-sort",
+	CodeInvalidCursor:         "invalid-cursor",
+	CodeEnrichmentInProgress:  "enrichment-in-progress",
+}
+
+func (e ErrorCodeEnum) String() string {
+	if name, ok := codeNames[e]; ok {
+		return name
+	}
+	return codeNames[CodeUnknown]
+}
+
 var (
 	ErrUnknown        = New(CodeUnknown, "unknown error")
 	ErrInvalidRequest = New(CodeInvalidRequest, "invalid request")
 	ErrUserNotFound   = New(CodeUserNotFound, "user not found")
 	ErrParamRequired  = New(CodeParamRequired, "user id param required")
+	ErrInvalidUserID  = New(CodeInvalidUserID, "user id must be a valid uuid")
+	ErrConflict       = New(CodeConflict, "resource already exists")
+	ErrUnprocessable  = New(CodeUnprocessable, "request violates data constraints")
+
+	ErrCacheEntryNotFound = New(CodeCacheEntryNotFound, "enrichment cache entry not found")
+
+	ErrEnrichmentJobNotFound = New(CodeEnrichmentJobNotFound, "enrichment job not found")
+	ErrInvalidJobID          = New(CodeInvalidJobID, "job id must be a valid uuid")
+	ErrInvalidSort           = New(CodeInvalidSort, "sort contains an unknown or repeated field")
+	ErrInvalidCursor         = New(CodeInvalidCursor, "cursor is malformed or was issued for a different sort")
+	ErrEnrichmentInProgress  = New(CodeEnrichmentInProgress, "user is being enriched, try again later")
+	ErrFilterRequired        = New(CodeInvalidRequest, "at least one filter or all=true is required")
 )
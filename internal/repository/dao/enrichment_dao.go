@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/pkg/database/postgres"
+)
+
+type EnrichmentCacheEntryDAO struct {
+	Name        string
+	CountryHint string
+	Attribute   string
+	Value       []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func (e *EnrichmentCacheEntryDAO) ToDomain() domain.EnrichmentCacheEntry {
+	return domain.EnrichmentCacheEntry{
+		Key: domain.EnrichmentCacheKey{
+			Name:        e.Name,
+			CountryHint: e.CountryHint,
+			Attribute:   domain.EnrichmentAttributeEnum(e.Attribute),
+		},
+		Value:     e.Value,
+		CreatedAt: e.CreatedAt,
+		ExpiresAt: e.ExpiresAt,
+	}
+}
+
+func (e *EnrichmentCacheEntryDAO) FromDomain(domain domain.EnrichmentCacheEntry) {
+	e.Name = domain.Key.Name
+	e.CountryHint = domain.Key.CountryHint
+	e.Attribute = string(domain.Key.Attribute)
+	e.Value = domain.Value
+	e.CreatedAt = domain.CreatedAt
+	e.ExpiresAt = domain.ExpiresAt
+}
+
+type EnrichmentCachePurgeFilterDAO struct {
+	Name        sql.NullString
+	ExpiredOnly bool
+}
+
+func (e *EnrichmentCachePurgeFilterDAO) FromDomain(domain domain.EnrichmentCachePurgeFilter) {
+	e.Name = postgres.ToNullString(domain.Name)
+	e.ExpiredOnly = domain.ExpiredOnly
+}
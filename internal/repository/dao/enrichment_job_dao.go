@@ -0,0 +1,30 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/google/uuid"
+)
+
+type EnrichmentJobDAO struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Force     bool
+	Total     int64
+	Pending   int64
+	Done      int64
+	Failed    int64
+}
+
+func (e *EnrichmentJobDAO) ToDomain() domain.EnrichmentJob {
+	return domain.EnrichmentJob{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt,
+		Force:     e.Force,
+		Total:     e.Total,
+		Pending:   e.Pending,
+		Done:      e.Done,
+		Failed:    e.Failed,
+	}
+}
@@ -0,0 +1,31 @@
+package dao
+
+import (
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/google/uuid"
+)
+
+type NationalityCandidateDAO struct {
+	UserID      uuid.UUID
+	CountryID   string
+	Probability float64
+}
+
+func (n *NationalityCandidateDAO) ToDomain() domain.NationalityCandidate {
+	return domain.NationalityCandidate{
+		CountryID:   n.CountryID,
+		Probability: n.Probability,
+	}
+}
+
+func NationalityCandidatesFromDomain(userID uuid.UUID, candidates []domain.NationalityCandidate) []NationalityCandidateDAO {
+	result := make([]NationalityCandidateDAO, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, NationalityCandidateDAO{
+			UserID:      userID,
+			CountryID:   candidate.CountryID,
+			Probability: candidate.Probability,
+		})
+	}
+	return result
+}
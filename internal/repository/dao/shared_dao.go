@@ -5,19 +5,55 @@ import (
 
 	"github.com/FlyKarlik/effectiveMobile/internal/domain"
 	"github.com/FlyKarlik/effectiveMobile/pkg/database/postgres"
+	"github.com/google/uuid"
 )
 
 type PaginationDAO struct {
-	Limit  sql.NullInt64
-	Offset sql.NullInt64
+	Limit     sql.NullInt64
+	Offset    sql.NullInt64
+	After     *KeysetDAO
+	WithTotal bool
 }
 
-func (p *PaginationDAO) FromDomain(domain domain.Pagination) {
-	if domain.Limit != 0 {
-		p.Limit = postgres.ToNullInt64(&domain.Limit)
+func (p *PaginationDAO) FromDomain(pagination domain.Pagination) {
+	p.WithTotal = pagination.Count == "" || pagination.Count == domain.ExactCountMode
+
+	if pagination.Limit != 0 {
+		p.Limit = postgres.ToNullInt64(&pagination.Limit)
+	}
+
+	if pagination.After != nil {
+		p.After = &KeysetDAO{Values: pagination.After.Values, ID: pagination.After.ID}
+		return
+	}
+
+	if pagination.Offset != 0 {
+		p.Offset = postgres.ToNullInt64(&pagination.Offset)
+	}
+}
+
+type KeysetDAO struct {
+	Values []*string
+	ID     uuid.UUID
+}
+
+func (k *KeysetDAO) ToDomain() domain.Keyset {
+	return domain.Keyset{
+		Values: k.Values,
+		ID:     k.ID,
 	}
+}
+
+type SortFieldDAO struct {
+	Field string
+	Desc  bool
+}
+
+type SortDAO []SortFieldDAO
 
-	if domain.Offset != 0 {
-		p.Offset = postgres.ToNullInt64(&domain.Offset)
+func (s *SortDAO) FromDomain(domain domain.Sort) {
+	*s = make(SortDAO, 0, len(domain))
+	for _, field := range domain {
+		*s = append(*s, SortFieldDAO{Field: field.Field, Desc: field.Desc})
 	}
 }
@@ -10,38 +10,68 @@ import (
 )
 
 type UserDAO struct {
-	ID          uuid.UUID
-	Name        string
-	Surname     string
-	Patronymic  sql.NullString
-	Nationality sql.NullString
-	Age         sql.NullInt64
-	Sex         sql.NullString
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID                     uuid.UUID
+	Name                   string
+	Surname                string
+	Patronymic             sql.NullString
+	Nationality            sql.NullString
+	Age                    sql.NullInt64
+	Sex                    sql.NullString
+	SexProbability         sql.NullFloat64
+	SexSource              sql.NullString
+	NationalityProbability sql.NullFloat64
+	EnrichmentSampleCount  sql.NullInt64
+	EnrichmentStatus       string
+	EnrichmentAttempts     int64
+	EnrichmentError        sql.NullString
+	EnrichmentJobID        uuid.NullUUID
+	EnrichmentForce        bool
+	CountryHint            sql.NullString
+	Score                  sql.NullFloat64
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
 }
 
 func (u *UserDAO) ToDomain() domain.User {
 	return domain.User{
-		ID:          u.ID,
-		CreateAt:    u.CreatedAt,
-		UpdateAt:    u.UpdatedAt,
-		Name:        u.Name,
-		Surname:     u.Surname,
-		Patronymic:  postgres.FromNullString(u.Patronymic),
-		Nationality: postgres.FromNullString(u.Nationality),
-		Age:         postgres.FromNullInt64(u.Age),
-		Sex:         (*domain.SexEnum)(postgres.FromNullString(u.Sex)),
+		ID:                     u.ID,
+		CreateAt:               u.CreatedAt,
+		UpdateAt:               u.UpdatedAt,
+		Name:                   u.Name,
+		Surname:                u.Surname,
+		Patronymic:             postgres.FromNullString(u.Patronymic),
+		Nationality:            postgres.FromNullString(u.Nationality),
+		Age:                    postgres.FromNullInt64(u.Age),
+		Sex:                    (*domain.SexEnum)(postgres.FromNullString(u.Sex)),
+		SexProbability:         postgres.FromNullFloat64(u.SexProbability),
+		SexSource:              (*domain.SexSourceEnum)(postgres.FromNullString(u.SexSource)),
+		NationalityProbability: postgres.FromNullFloat64(u.NationalityProbability),
+		EnrichmentSampleCount:  postgres.FromNullInt64(u.EnrichmentSampleCount),
+		EnrichmentStatus:       domain.EnrichmentStatusEnum(u.EnrichmentStatus),
+		EnrichmentAttempts:     u.EnrichmentAttempts,
+		EnrichmentError:        postgres.FromNullString(u.EnrichmentError),
+		EnrichmentJobID:        postgres.FromNullUUID(u.EnrichmentJobID),
+		EnrichmentForce:        u.EnrichmentForce,
+		CountryHint:            postgres.FromNullString(u.CountryHint),
+		Score:                  postgres.FromNullFloat64(u.Score),
 	}
 }
 
 type CreateUserInputDAO struct {
-	Name        string
-	Surname     string
-	Patronymic  sql.NullString
-	Nationality sql.NullString
-	Age         sql.NullInt64
-	Sex         sql.NullString
+	Name                   string
+	Surname                string
+	Patronymic             sql.NullString
+	Nationality            sql.NullString
+	Age                    sql.NullInt64
+	Sex                    sql.NullString
+	SexProbability         sql.NullFloat64
+	SexSource              sql.NullString
+	NationalityProbability sql.NullFloat64
+	EnrichmentSampleCount  sql.NullInt64
+	EnrichmentStatus       string
+	EnrichmentAttempts     int64
+	EnrichmentError        sql.NullString
+	CountryHint            sql.NullString
 }
 
 func (c *CreateUserInputDAO) FromDomain(domain domain.CreateUserInput) {
@@ -51,6 +81,42 @@ func (c *CreateUserInputDAO) FromDomain(domain domain.CreateUserInput) {
 	c.Nationality = postgres.ToNullString(domain.Nationality)
 	c.Age = postgres.ToNullInt64(domain.Age)
 	c.Sex = postgres.ToNullString((*string)(domain.Sex))
+	c.SexProbability = postgres.ToNullFloat64(domain.SexProbability)
+	c.SexSource = postgres.ToNullString((*string)(domain.SexSource))
+	c.NationalityProbability = postgres.ToNullFloat64(domain.NationalityProbability)
+	c.EnrichmentSampleCount = postgres.ToNullInt64(domain.EnrichmentSampleCount)
+	c.EnrichmentStatus = string(domain.EnrichmentStatus)
+	c.EnrichmentAttempts = domain.EnrichmentAttempts
+	c.EnrichmentError = postgres.ToNullString(domain.EnrichmentError)
+	c.CountryHint = postgres.ToNullString(domain.CountryHint)
+}
+
+type UserEnrichmentDAO struct {
+	Nationality            sql.NullString
+	Age                    sql.NullInt64
+	Sex                    sql.NullString
+	SexProbability         sql.NullFloat64
+	SexSource              sql.NullString
+	NationalityProbability sql.NullFloat64
+	EnrichmentSampleCount  sql.NullInt64
+	Force                  bool
+	Status                 string
+	Error                  sql.NullString
+	NextAttemptAt          time.Time
+}
+
+func (u *UserEnrichmentDAO) FromDomain(enrichment domain.UserEnrichment) {
+	u.Nationality = postgres.ToNullString(enrichment.Nationality)
+	u.Age = postgres.ToNullInt64(enrichment.Age)
+	u.Sex = postgres.ToNullString((*string)(enrichment.Sex))
+	u.SexProbability = postgres.ToNullFloat64(enrichment.SexProbability)
+	u.SexSource = postgres.ToNullString((*string)(enrichment.SexSource))
+	u.NationalityProbability = postgres.ToNullFloat64(enrichment.NationalityProbability)
+	u.EnrichmentSampleCount = postgres.ToNullInt64(enrichment.EnrichmentSampleCount)
+	u.Force = enrichment.Force
+	u.Status = string(enrichment.Status)
+	u.Error = postgres.ToNullString(enrichment.Error)
+	u.NextAttemptAt = enrichment.NextAttemptAt
 }
 
 type UpdateUserInputDAO struct {
@@ -60,31 +126,63 @@ type UpdateUserInputDAO struct {
 	Nationality sql.NullString
 	Age         sql.NullInt64
 	Sex         sql.NullString
+	SexSource   sql.NullString
 }
 
-func (u *UpdateUserInputDAO) FromDomain(domain domain.UpdateUserInput) {
-	u.Name = postgres.ToNullString(domain.Name)
-	u.Surname = postgres.ToNullString(domain.Surname)
-	u.Patronymic = postgres.ToNullString(domain.Patronymic)
-	u.Nationality = postgres.ToNullString(domain.Nationality)
-	u.Age = postgres.ToNullInt64(domain.Age)
-	u.Sex = postgres.ToNullString((*string)(domain.Sex))
+func (u *UpdateUserInputDAO) FromDomain(input domain.UpdateUserInput) {
+	u.Name = postgres.ToNullString(input.Name)
+	u.Surname = postgres.ToNullString(input.Surname)
+	u.Patronymic = postgres.ToNullString(input.Patronymic)
+	u.Nationality = postgres.ToNullString(input.Nationality)
+	u.Age = postgres.ToNullInt64(input.Age)
+	u.Sex = postgres.ToNullString((*string)(input.Sex))
+
+	// Sex set through the API overrides whatever was predicted.
+	if input.Sex != nil {
+		manual := string(domain.ManualSexSource)
+		u.SexSource = postgres.ToNullString(&manual)
+	}
 }
 
 type UserFilterDAO struct {
-	Name        sql.NullString
-	Surname     sql.NullString
-	Patronymic  sql.NullString
-	Nationality sql.NullString
-	Sex         sql.NullString
-	Age         sql.NullInt64
+	Query                     sql.NullString
+	QueryThreshold            float64
+	Name                      sql.NullString
+	Surname                   sql.NullString
+	Patronymic                sql.NullString
+	Nationalities             []string
+	Sex                       sql.NullString
+	Age                       sql.NullInt64
+	AgeMin                    sql.NullInt64
+	AgeMax                    sql.NullInt64
+	HasAge                    sql.NullBool
+	CreatedFrom               sql.NullTime
+	CreatedTo                 sql.NullTime
+	UpdatedSince              sql.NullTime
+	MinSexProbability         sql.NullFloat64
+	MinNationalityProbability sql.NullFloat64
+	NationalityCandidate      sql.NullString
+	SexSource                 sql.NullString
+	EnrichmentStatus          sql.NullString
 }
 
 func (u *UserFilterDAO) FromDomain(domain domain.UserFilter) {
+	u.Query = postgres.ToNullString(domain.Query)
 	u.Name = postgres.ToNullString(domain.Name)
 	u.Surname = postgres.ToNullString(domain.Surname)
 	u.Patronymic = postgres.ToNullString(domain.Patronymic)
-	u.Nationality = postgres.ToNullString(domain.Nationality)
+	u.Nationalities = domain.Nationalities
 	u.Sex = postgres.ToNullString((*string)(domain.Sex))
 	u.Age = postgres.ToNullInt64(domain.Age)
+	u.AgeMin = postgres.ToNullInt64(domain.AgeMin)
+	u.AgeMax = postgres.ToNullInt64(domain.AgeMax)
+	u.HasAge = postgres.ToNullBool(domain.HasAge)
+	u.CreatedFrom = postgres.ToNullTime(domain.CreatedFrom)
+	u.CreatedTo = postgres.ToNullTime(domain.CreatedTo)
+	u.UpdatedSince = postgres.ToNullTime(domain.UpdatedSince)
+	u.MinSexProbability = postgres.ToNullFloat64(domain.MinSexProbability)
+	u.MinNationalityProbability = postgres.ToNullFloat64(domain.MinNationalityProbability)
+	u.NationalityCandidate = postgres.ToNullString(domain.NationalityCandidate)
+	u.SexSource = postgres.ToNullString((*string)(domain.SexSource))
+	u.EnrichmentStatus = postgres.ToNullString((*string)(domain.EnrichmentStatus))
 }
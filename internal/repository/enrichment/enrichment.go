@@ -0,0 +1,120 @@
+package enrichment_repo
+
+import (
+	"context"
+
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
+	"github.com/FlyKarlik/effectiveMobile/internal/repository/dao"
+	"github.com/FlyKarlik/effectiveMobile/internal/repository/queries"
+	"github.com/FlyKarlik/effectiveMobile/pkg/database/postgres"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+)
+
+type IEnrichmentCacheRepository interface {
+	GetCacheEntry(ctx context.Context, key domain.EnrichmentCacheKey) (domain.EnrichmentCacheEntry, error)
+	SetCacheEntry(ctx context.Context, entry domain.EnrichmentCacheEntry) error
+	PurgeCache(ctx context.Context, filter domain.EnrichmentCachePurgeFilter) (int64, error)
+}
+
+type enrichmentRepo struct {
+	logger logger.Logger
+	q      postgres.Querier
+}
+
+func New(logger logger.Logger, q postgres.Querier) IEnrichmentCacheRepository {
+	return &enrichmentRepo{
+		logger: logger,
+		q:      q,
+	}
+}
+
+func (e *enrichmentRepo) GetCacheEntry(ctx context.Context, key domain.EnrichmentCacheKey) (domain.EnrichmentCacheEntry, error) {
+	const layer string = "repository"
+	const method = "GetCacheEntry"
+
+	e.logger.Debug(layer, method, "started", "key", key)
+
+	query, args, err := queries.BuildGetEnrichmentCacheEntryQuery(key.Name, key.CountryHint, string(key.Attribute))
+	if err != nil {
+		e.logger.Error(layer, method, "failed to build query", err, "key", key)
+		return domain.EnrichmentCacheEntry{}, err
+	}
+
+	e.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	var entry dao.EnrichmentCacheEntryDAO
+	err = e.q.QueryRow(ctx, query, args...).Scan(
+		&entry.Name,
+		&entry.CountryHint,
+		&entry.Attribute,
+		&entry.Value,
+		&entry.CreatedAt,
+		&entry.ExpiresAt,
+	)
+	if err != nil {
+		if postgres.IsNoRows(err) {
+			e.logger.Debug(layer, method, "cache entry not found", "key", key)
+			return domain.EnrichmentCacheEntry{}, errs.ErrCacheEntryNotFound
+		}
+		e.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return domain.EnrichmentCacheEntry{}, err
+	}
+
+	result := entry.ToDomain()
+	e.logger.Debug(layer, method, "successfully completed", "key", key)
+	return result, nil
+}
+
+func (e *enrichmentRepo) SetCacheEntry(ctx context.Context, entry domain.EnrichmentCacheEntry) error {
+	const layer string = "repository"
+	const method = "SetCacheEntry"
+
+	e.logger.Debug(layer, method, "started", "key", entry.Key)
+
+	entryDAO := new(dao.EnrichmentCacheEntryDAO)
+	entryDAO.FromDomain(entry)
+
+	query, args, err := queries.BuildUpsertEnrichmentCacheEntryQuery(*entryDAO)
+	if err != nil {
+		e.logger.Error(layer, method, "failed to build query", err, "key", entry.Key)
+		return err
+	}
+
+	e.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	if _, err := e.q.Exec(ctx, query, args...); err != nil {
+		e.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return err
+	}
+
+	e.logger.Debug(layer, method, "successfully completed", "key", entry.Key)
+	return nil
+}
+
+func (e *enrichmentRepo) PurgeCache(ctx context.Context, filter domain.EnrichmentCachePurgeFilter) (int64, error) {
+	const layer string = "repository"
+	const method = "PurgeCache"
+
+	e.logger.Debug(layer, method, "started", "filter", filter)
+
+	filterDAO := new(dao.EnrichmentCachePurgeFilterDAO)
+	filterDAO.FromDomain(filter)
+
+	query, args, err := queries.BuildPurgeEnrichmentCacheQuery(*filterDAO)
+	if err != nil {
+		e.logger.Error(layer, method, "failed to build query", err, "filter", filter)
+		return 0, err
+	}
+
+	e.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	tag, err := e.q.Exec(ctx, query, args...)
+	if err != nil {
+		e.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return 0, err
+	}
+
+	e.logger.Debug(layer, method, "successfully completed", "purged", tag.RowsAffected())
+	return tag.RowsAffected(), nil
+}
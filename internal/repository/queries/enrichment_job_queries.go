@@ -0,0 +1,47 @@
+package queries
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+func BuildCreateEnrichmentJobQuery(force bool) (string, []interface{}, error) {
+	builder := sq.Insert("enrichment_job").
+		Columns("force").
+		Values(force).
+		PlaceholderFormat(sq.Dollar).
+		Suffix("RETURNING id, created_at, force")
+
+	return builder.ToSql()
+}
+
+func BuildGetEnrichmentJobQuery(id uuid.UUID) (string, []interface{}, error) {
+	builder := sq.Select(
+		"j.id",
+		"j.created_at",
+		"j.force",
+		"COUNT(i.user_id)",
+		"COUNT(i.user_id) FILTER (WHERE i.status = 'pending')",
+		"COUNT(i.user_id) FILTER (WHERE i.status = 'done')",
+		"COUNT(i.user_id) FILTER (WHERE i.status = 'failed')",
+	).
+		From("enrichment_job j").
+		LeftJoin("enrichment_job_user i ON i.job_id = j.id").
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{"j.id": id}).
+		GroupBy("j.id")
+
+	return builder.ToSql()
+}
+
+// BuildResolveEnrichmentJobUsersQuery records the final outcome of a user in
+// every job still waiting for it. A user queued by several jobs before a
+// worker reached it is enriched once, and that run settles all of them.
+func BuildResolveEnrichmentJobUsersQuery(userID uuid.UUID, status string) (string, []interface{}, error) {
+	builder := sq.Update("enrichment_job_user").
+		Set("status", status).
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{"user_id": userID, "status": "pending"})
+
+	return builder.ToSql()
+}
@@ -0,0 +1,47 @@
+package queries
+
+import (
+	"github.com/FlyKarlik/effectiveMobile/internal/repository/dao"
+	sq "github.com/Masterminds/squirrel"
+)
+
+func BuildGetEnrichmentCacheEntryQuery(name string, countryHint string, attribute string) (string, []interface{}, error) {
+	builder := sq.Select(`"name"`, "country_hint", "attribute", `"value"`, "created_at", "expires_at").
+		From("enrichment_cache").
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{
+			`"name"`:       name,
+			"country_hint": countryHint,
+			"attribute":    attribute,
+		}).
+		Where("expires_at > NOW()")
+
+	return builder.ToSql()
+}
+
+func BuildUpsertEnrichmentCacheEntryQuery(entry dao.EnrichmentCacheEntryDAO) (string, []interface{}, error) {
+	builder := sq.Insert("enrichment_cache").
+		Columns(`"name"`, "country_hint", "attribute", `"value"`, "created_at", "expires_at").
+		Values(entry.Name, entry.CountryHint, entry.Attribute, entry.Value, entry.CreatedAt, entry.ExpiresAt).
+		PlaceholderFormat(sq.Dollar).
+		Suffix(`ON CONFLICT ("name", country_hint, attribute) DO UPDATE SET
+			"value" = EXCLUDED."value",
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at`)
+
+	return builder.ToSql()
+}
+
+func BuildPurgeEnrichmentCacheQuery(filter dao.EnrichmentCachePurgeFilterDAO) (string, []interface{}, error) {
+	builder := sq.Delete("enrichment_cache").PlaceholderFormat(sq.Dollar)
+
+	if filter.Name.Valid {
+		builder = builder.Where(sq.Eq{`"name"`: filter.Name.String})
+	}
+
+	if filter.ExpiredOnly {
+		builder = builder.Where("expires_at <= NOW()")
+	}
+
+	return builder.ToSql()
+}
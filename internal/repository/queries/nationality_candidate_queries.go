@@ -0,0 +1,39 @@
+package queries
+
+import (
+	"github.com/FlyKarlik/effectiveMobile/internal/repository/dao"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+func BuildInsertNationalityCandidatesQuery(candidates []dao.NationalityCandidateDAO) (string, []interface{}, error) {
+	builder := sq.Insert("user_nationality_candidate").
+		Columns("user_id", "country_id", "probability").
+		PlaceholderFormat(sq.Dollar)
+
+	for _, candidate := range candidates {
+		builder = builder.Values(candidate.UserID, candidate.CountryID, candidate.Probability)
+	}
+
+	builder = builder.Suffix("ON CONFLICT (user_id, country_id) DO UPDATE SET probability = EXCLUDED.probability")
+
+	return builder.ToSql()
+}
+
+func BuildDeleteNationalityCandidatesQuery(userID uuid.UUID) (string, []interface{}, error) {
+	builder := sq.Delete("user_nationality_candidate").
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{"user_id": userID})
+
+	return builder.ToSql()
+}
+
+func BuildGetNationalityCandidatesQuery(userIDs []uuid.UUID) (string, []interface{}, error) {
+	builder := sq.Select("user_id", "country_id", "probability").
+		From("user_nationality_candidate").
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{"user_id": userIDs}).
+		OrderBy("user_id", "probability DESC")
+
+	return builder.ToSql()
+}
@@ -1,82 +1,450 @@
 package queries
 
 import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/FlyKarlik/effectiveMobile/internal/repository/dao"
+	"github.com/FlyKarlik/effectiveMobile/pkg/database/postgres"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 )
 
+var userColumns = []string{
+	"id",
+	"created_at",
+	"updated_at",
+	"name",
+	"surname",
+	"nationality",
+	"patronymic",
+	"sex",
+	"age",
+	"sex_probability",
+	"sex_source",
+	"nationality_probability",
+	"enrichment_sample_count",
+	"enrichment_status",
+	"enrichment_attempts",
+	"enrichment_error",
+	"enrichment_job_id",
+	"enrichment_force",
+	"enrichment_country_hint",
+}
+
+var returningUserColumns = "RETURNING " + strings.Join(userColumns, ", ")
+
+// userFullName is the text the full-name search matches against. It is what
+// idx_user_full_name_trgm indexes and has to stay identical to it.
+const userFullName = `(surname || ' ' || "name" || ' ' || COALESCE(patronymic, ''))`
+
+var (
+	ErrUnsupportedSortField = errors.New("unsupported sort field")
+	ErrKeysetMismatch       = errors.New("keyset does not match the sort")
+)
+
+// sortColumn is a column users can be sorted by. The type is used to cast
+// cursor values, which travel as text, and value reads the column back from a
+// row to build the cursor of the next page.
+type sortColumn struct {
+	name     string
+	sqlType  string
+	nullable bool
+	value    func(user dao.UserDAO) *string
+}
+
+// userSortColumns whitelists the fields users can be sorted by and maps them
+// to columns, so the sort parameter never reaches the SQL text as is.
+var userSortColumns = map[string]sortColumn{
+	"id": {name: "id", sqlType: "uuid", value: func(u dao.UserDAO) *string {
+		return textValue(u.ID.String())
+	}},
+	"name": {name: "name", sqlType: "text", value: func(u dao.UserDAO) *string {
+		return textValue(u.Name)
+	}},
+	"surname": {name: "surname", sqlType: "text", value: func(u dao.UserDAO) *string {
+		return textValue(u.Surname)
+	}},
+	"patronymic": {name: "patronymic", sqlType: "text", nullable: true, value: func(u dao.UserDAO) *string {
+		return postgres.FromNullString(u.Patronymic)
+	}},
+	"nationality": {name: "nationality", sqlType: "bpchar", nullable: true, value: func(u dao.UserDAO) *string {
+		return postgres.FromNullString(u.Nationality)
+	}},
+	"sex": {name: "sex", sqlType: "text", nullable: true, value: func(u dao.UserDAO) *string {
+		return postgres.FromNullString(u.Sex)
+	}},
+	"age": {name: "age", sqlType: "bigint", nullable: true, value: func(u dao.UserDAO) *string {
+		if !u.Age.Valid {
+			return nil
+		}
+		return textValue(strconv.FormatInt(u.Age.Int64, 10))
+	}},
+	"sex_probability": {name: "sex_probability", sqlType: "float8", nullable: true, value: func(u dao.UserDAO) *string {
+		return floatValue(u.SexProbability)
+	}},
+	"nationality_probability": {name: "nationality_probability", sqlType: "float8", nullable: true, value: func(u dao.UserDAO) *string {
+		return floatValue(u.NationalityProbability)
+	}},
+	"enrichment_status": {name: "enrichment_status", sqlType: "text", value: func(u dao.UserDAO) *string {
+		return textValue(u.EnrichmentStatus)
+	}},
+	"created_at": {name: "created_at", sqlType: "timestamptz", value: func(u dao.UserDAO) *string {
+		return textValue(u.CreatedAt.Format(time.RFC3339Nano))
+	}},
+	"updated_at": {name: "updated_at", sqlType: "timestamptz", value: func(u dao.UserDAO) *string {
+		return textValue(u.UpdatedAt.Format(time.RFC3339Nano))
+	}},
+	// score only exists in a search by full name.
+	"score": {name: "score", sqlType: "float8", value: func(u dao.UserDAO) *string {
+		return floatValue(u.Score)
+	}},
+}
+
+func textValue(s string) *string {
+	return &s
+}
+
+func floatValue(f sql.NullFloat64) *string {
+	if !f.Valid {
+		return nil
+	}
+	return textValue(strconv.FormatFloat(f.Float64, 'g', -1, 64))
+}
+
 func BuildCountUsersQuery(filter dao.UserFilterDAO) (string, []interface{}, error) {
 	builder := sq.Select("COUNT(*)").From(`"user"`).PlaceholderFormat(sq.Dollar)
+	if predicate := userFilterPredicate(filter); len(predicate) > 0 {
+		builder = builder.Where(predicate)
+	}
 
-	if filter.Name.Valid {
-		builder = builder.Where(sq.ILike{"name": "%" + filter.Name.String + "%"})
+	return builder.ToSql()
+}
+
+// BuildSearchUsersQuery selects a page of users. After userColumns come the
+// score, for a search by full name, and then the total number of matching
+// users, when pagination asks for it. Either makes the filtered selection an
+// inner query, so that the score can be sorted and paginated by like any
+// other column and the total is counted before the page is cut out of it.
+func BuildSearchUsersQuery(filter dao.UserFilterDAO, sort dao.SortDAO, pagination dao.PaginationDAO) (string, []interface{}, error) {
+	builder := sq.Select(userColumns...).From(`"user"`)
+	if predicate := userFilterPredicate(filter); len(predicate) > 0 {
+		builder = builder.Where(predicate)
 	}
 
-	if filter.Surname.Valid {
-		builder = builder.Where(sq.ILike{"surname": "%" + filter.Surname.String + "%"})
+	if filter.Query.Valid {
+		builder = builder.Column(sq.Expr("word_similarity(?, "+userFullName+")::float8 AS score", filter.Query.String))
+	} else if slices.ContainsFunc(sort, func(field dao.SortFieldDAO) bool { return field.Field == "score" }) {
+		return "", nil, ErrUnsupportedSortField
 	}
 
-	if filter.Patronymic.Valid {
-		builder = builder.Where(sq.ILike{"patronymic": "%" + filter.Patronymic.String + "%"})
+	if pagination.WithTotal {
+		builder = builder.Column("COUNT(*) OVER () AS total")
 	}
 
-	if filter.Nationality.Valid {
-		builder = builder.Where(sq.Eq{"nationality": filter.Nationality.String})
+	if filter.Query.Valid || pagination.WithTotal {
+		builder = sq.Select("*").FromSelect(builder, "u")
 	}
+	builder = builder.PlaceholderFormat(sq.Dollar)
 
-	if filter.Sex.Valid {
-		builder = builder.Where(sq.Eq{"sex": filter.Sex.String})
+	keys, err := userSortKeys(sort)
+	if err != nil {
+		return "", nil, err
 	}
 
-	if filter.Age.Valid {
-		builder = builder.Where(sq.Eq{"age": filter.Age.Int64})
+	if pagination.After != nil {
+		if len(pagination.After.Values) != len(sort) {
+			return "", nil, ErrKeysetMismatch
+		}
+
+		after, err := keysetAfter(keys, *pagination.After)
+		if err != nil {
+			return "", nil, err
+		}
+		builder = builder.Where(after)
 	}
 
+	builder = builder.OrderBy(orderBy(keys)...)
+
+	if pagination.Limit.Valid {
+		builder = builder.Limit(uint64(pagination.Limit.Int64))
+	}
+	if pagination.Offset.Valid {
+		builder = builder.Offset(uint64(pagination.Offset.Int64))
+	}
 	return builder.ToSql()
 }
-func BuildSearchUsersQuery(filter dao.UserFilterDAO, pagination dao.PaginationDAO) (string, []interface{}, error) {
-	builder := sq.Select("*").From(`"user"`).PlaceholderFormat(sq.Dollar)
+
+type sortKey struct {
+	column sortColumn
+	desc   bool
+}
+
+// userSortKeys resolves the sort against the whitelist and appends id as a
+// tie-breaker so that the order is total and pages never overlap.
+func userSortKeys(sort dao.SortDAO) ([]sortKey, error) {
+	keys := make([]sortKey, 0, len(sort)+1)
+	seen := make(map[string]bool, len(sort))
+
+	for _, field := range sort {
+		column, ok := userSortColumns[field.Field]
+		if !ok || seen[column.name] {
+			return nil, ErrUnsupportedSortField
+		}
+		seen[column.name] = true
+		keys = append(keys, sortKey{column: column, desc: field.Desc})
+	}
+
+	if !seen["id"] {
+		keys = append(keys, sortKey{column: userSortColumns["id"]})
+	}
+	return keys, nil
+}
+
+// orderBy turns sort keys into ORDER BY terms. Missing values always come
+// last, whatever the direction.
+func orderBy(keys []sortKey) []string {
+	terms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.desc {
+			terms = append(terms, key.column.name+" DESC NULLS LAST")
+		} else {
+			terms = append(terms, key.column.name+" ASC NULLS LAST")
+		}
+	}
+	return terms
+}
+
+// UserKeyset returns the position of user in the given sort, in the form a
+// cursor for the following page needs.
+func UserKeyset(user dao.UserDAO, sort dao.SortDAO) (dao.KeysetDAO, error) {
+	keys, err := userSortKeys(sort)
+	if err != nil {
+		return dao.KeysetDAO{}, err
+	}
+
+	keyset := dao.KeysetDAO{Values: make([]*string, 0, len(sort)), ID: user.ID}
+	for _, key := range keys[:len(sort)] {
+		keyset.Values = append(keyset.Values, key.column.value(user))
+	}
+	return keyset, nil
+}
+
+// keysetAfter selects the rows that come after the keyset in the order of
+// keys. When every key is NOT NULL and sorted the same way this is a plain
+// row comparison, (a, b, id) > (?, ?, ?), which an index on the same columns
+// can serve. Otherwise it is expanded into one branch per key, because row
+// comparison can neither mix directions nor place NULLs last.
+func keysetAfter(keys []sortKey, after dao.KeysetDAO) (sq.Sqlizer, error) {
+	values := after.Values
+	if len(keys) == len(values)+1 {
+		values = append(slices.Clone(values), textValue(after.ID.String()))
+	}
+
+	if sameDirectionNotNull(keys) {
+		columns := make([]string, 0, len(keys))
+		placeholders := make([]string, 0, len(keys))
+		args := make([]interface{}, 0, len(keys))
+		for i, key := range keys {
+			if values[i] == nil {
+				return nil, ErrKeysetMismatch
+			}
+			columns = append(columns, key.column.name)
+			placeholders = append(placeholders, "?::"+key.column.sqlType)
+			args = append(args, *values[i])
+		}
+
+		operator := ">"
+		if keys[0].desc {
+			operator = "<"
+		}
+		return sq.Expr("("+strings.Join(columns, ", ")+") "+operator+" ("+strings.Join(placeholders, ", ")+")", args...), nil
+	}
+
+	branches := sq.Or{}
+	for i, key := range keys {
+		branch := sq.And{}
+		for j := 0; j < i; j++ {
+			branch = append(branch, keysetEqual(keys[j], values[j]))
+		}
+
+		// Nothing sorts after NULL on its own key, since NULLs come last.
+		if values[i] == nil {
+			continue
+		}
+		branch = append(branch, keysetGreater(key, *values[i]))
+		branches = append(branches, branch)
+	}
+
+	if len(branches) == 0 {
+		return sq.Expr("FALSE"), nil
+	}
+	return branches, nil
+}
+
+func sameDirectionNotNull(keys []sortKey) bool {
+	for _, key := range keys {
+		if key.column.nullable || key.desc != keys[0].desc {
+			return false
+		}
+	}
+	return true
+}
+
+func keysetEqual(key sortKey, value *string) sq.Sqlizer {
+	if value == nil {
+		return sq.Expr(key.column.name + " IS NULL")
+	}
+	return sq.Expr(key.column.name+" = ?::"+key.column.sqlType, *value)
+}
+
+func keysetGreater(key sortKey, value string) sq.Sqlizer {
+	operator := ">"
+	if key.desc {
+		operator = "<"
+	}
+
+	comparison := sq.Expr(key.column.name+" "+operator+" ?::"+key.column.sqlType, value)
+	if !key.column.nullable {
+		return comparison
+	}
+	return sq.Or{comparison, sq.Expr(key.column.name + " IS NULL")}
+}
+
+// userFilterPredicate builds the WHERE conditions of a user filter once for
+// every query that selects users by it. It is empty when nothing is filtered.
+func userFilterPredicate(filter dao.UserFilterDAO) sq.And {
+	predicate := sq.And{}
+
+	if filter.Query.Valid {
+		// <% is what the trigram index serves, but it compares against the
+		// pg_trgm.word_similarity_threshold setting. The explicit comparison
+		// keeps the configured threshold where the setting was not applied.
+		predicate = append(predicate,
+			sq.Expr("? <% "+userFullName, filter.Query.String),
+			sq.Expr("word_similarity(?, "+userFullName+") >= ?", filter.Query.String, filter.QueryThreshold),
+		)
+	}
 
 	if filter.Name.Valid {
-		builder = builder.Where(sq.ILike{"name": "%" + filter.Name.String + "%"})
+		predicate = append(predicate, sq.ILike{"name": "%" + filter.Name.String + "%"})
 	}
 
 	if filter.Surname.Valid {
-		builder = builder.Where(sq.ILike{"surname": "%" + filter.Surname.String + "%"})
+		predicate = append(predicate, sq.ILike{"surname": "%" + filter.Surname.String + "%"})
 	}
 
 	if filter.Patronymic.Valid {
-		builder = builder.Where(sq.ILike{"patronymic": "%" + filter.Patronymic.String + "%"})
+		predicate = append(predicate, sq.ILike{"patronymic": "%" + filter.Patronymic.String + "%"})
 	}
 
-	if filter.Nationality.Valid {
-		builder = builder.Where(sq.Eq{"nationality": filter.Nationality.String})
+	if len(filter.Nationalities) > 0 {
+		predicate = append(predicate, sq.Eq{"nationality": filter.Nationalities})
 	}
 
 	if filter.Sex.Valid {
-		builder = builder.Where(sq.Eq{"sex": filter.Sex.String})
+		predicate = append(predicate, sq.Eq{"sex": filter.Sex.String})
 	}
 
 	if filter.Age.Valid {
-		builder = builder.Where(sq.Eq{"age": filter.Age.Int64})
+		predicate = append(predicate, sq.Eq{"age": filter.Age.Int64})
 	}
 
-	if pagination.Limit.Valid {
-		builder = builder.Limit(uint64(pagination.Limit.Int64))
+	if filter.AgeMin.Valid {
+		predicate = append(predicate, sq.GtOrEq{"age": filter.AgeMin.Int64})
 	}
-	if pagination.Offset.Valid {
-		builder = builder.Offset(uint64(pagination.Offset.Int64))
+
+	if filter.AgeMax.Valid {
+		predicate = append(predicate, sq.LtOrEq{"age": filter.AgeMax.Int64})
 	}
+
+	if filter.HasAge.Valid {
+		if filter.HasAge.Bool {
+			predicate = append(predicate, sq.NotEq{"age": nil})
+		} else {
+			predicate = append(predicate, sq.Eq{"age": nil})
+		}
+	}
+
+	if filter.CreatedFrom.Valid {
+		predicate = append(predicate, sq.GtOrEq{"created_at": filter.CreatedFrom.Time})
+	}
+
+	if filter.CreatedTo.Valid {
+		predicate = append(predicate, sq.Lt{"created_at": filter.CreatedTo.Time})
+	}
+
+	if filter.UpdatedSince.Valid {
+		predicate = append(predicate, sq.GtOrEq{"updated_at": filter.UpdatedSince.Time})
+	}
+
+	if filter.MinSexProbability.Valid {
+		predicate = append(predicate, sq.GtOrEq{"sex_probability": filter.MinSexProbability.Float64})
+	}
+
+	if filter.MinNationalityProbability.Valid {
+		predicate = append(predicate, sq.GtOrEq{"nationality_probability": filter.MinNationalityProbability.Float64})
+	}
+
+	if filter.SexSource.Valid {
+		predicate = append(predicate, sq.Eq{"sex_source": filter.SexSource.String})
+	}
+
+	if filter.EnrichmentStatus.Valid {
+		predicate = append(predicate, sq.Eq{"enrichment_status": filter.EnrichmentStatus.String})
+	}
+
+	if filter.NationalityCandidate.Valid {
+		predicate = append(predicate, sq.Expr(`EXISTS (
+			SELECT 1 FROM user_nationality_candidate c
+			WHERE c.user_id = "user".id AND c.country_id = ?
+		)`, filter.NationalityCandidate.String))
+	}
+
+	return predicate
+}
+
+// BuildEstimateUsersQuery asks the planner how many users match the filter.
+// The plan comes back as JSON, with the estimate in the Plan Rows of its root.
+func BuildEstimateUsersQuery(filter dao.UserFilterDAO) (string, []interface{}, error) {
+	builder := sq.Select("1").From(`"user"`).PlaceholderFormat(sq.Dollar)
+	if predicate := userFilterPredicate(filter); len(predicate) > 0 {
+		builder = builder.Where(predicate)
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return "EXPLAIN (FORMAT JSON) " + query, args, nil
+}
+
+// BuildSetSimilarityThresholdQuery sets the threshold of the <% operator for
+// the rest of the current transaction.
+func BuildSetSimilarityThresholdQuery(threshold float64) (string, []interface{}, error) {
+	return "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)",
+		[]interface{}{strconv.FormatFloat(threshold, 'g', -1, 64)}, nil
+}
+
+func BuildGetUserByIDQuery(id uuid.UUID) (string, []interface{}, error) {
+	builder := sq.Select(userColumns...).
+		From(`"user"`).
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{"id": id})
+
 	return builder.ToSql()
 }
 
 func BuildCreateUserQuery(user dao.CreateUserInputDAO) (string, []interface{}, error) {
 	values := map[string]interface{}{
-		"name":    user.Name,
-		"surname": user.Surname,
+		"name":                user.Name,
+		"surname":             user.Surname,
+		"enrichment_status":   user.EnrichmentStatus,
+		"enrichment_attempts": user.EnrichmentAttempts,
 	}
 
 	if user.Patronymic.Valid {
@@ -95,10 +463,34 @@ func BuildCreateUserQuery(user dao.CreateUserInputDAO) (string, []interface{}, e
 		values["sex"] = user.Sex.String
 	}
 
+	if user.SexProbability.Valid {
+		values["sex_probability"] = user.SexProbability.Float64
+	}
+
+	if user.SexSource.Valid {
+		values["sex_source"] = user.SexSource.String
+	}
+
+	if user.NationalityProbability.Valid {
+		values["nationality_probability"] = user.NationalityProbability.Float64
+	}
+
+	if user.EnrichmentSampleCount.Valid {
+		values["enrichment_sample_count"] = user.EnrichmentSampleCount.Int64
+	}
+
+	if user.EnrichmentError.Valid {
+		values["enrichment_error"] = user.EnrichmentError.String
+	}
+
+	if user.CountryHint.Valid {
+		values["enrichment_country_hint"] = user.CountryHint.String
+	}
+
 	builder := sq.Insert(`"user"`).
 		SetMap(values).
 		PlaceholderFormat(sq.Dollar).
-		Suffix("RETURNING *")
+		Suffix(returningUserColumns)
 
 	return builder.ToSql()
 }
@@ -122,8 +514,11 @@ func BuildUpdateUserQuery(id uuid.UUID, user dao.UpdateUserInputDAO) (string, []
 		builder = builder.Set("patronymic", user.Patronymic.String)
 	}
 
+	// A manually supplied value is no longer a prediction, so the stored
+	// confidence would be misleading.
 	if user.Nationality.Valid {
 		builder = builder.Set("nationality", user.Nationality.String)
+		builder = builder.Set("nationality_probability", nil)
 	}
 
 	if user.Age.Valid {
@@ -132,18 +527,194 @@ func BuildUpdateUserQuery(id uuid.UUID, user dao.UpdateUserInputDAO) (string, []
 
 	if user.Sex.Valid {
 		builder = builder.Set("sex", user.Sex.String)
+		builder = builder.Set("sex_probability", nil)
+	}
+
+	if user.SexSource.Valid {
+		builder = builder.Set("sex_source", user.SexSource.String)
 	}
 
-	builder = builder.Suffix("RETURNING *")
+	builder = builder.Suffix(returningUserColumns)
 
 	return builder.ToSql()
 }
 
+// BuildClaimPendingUsersQuery claims up to limit users due for enrichment:
+// pending users whose retry time has come, or processing ones whose lease has
+// run out because their worker never wrote a result back. SKIP LOCKED keeps
+// concurrent workers from picking the same rows, and the statement commits on
+// its own, so nothing stays locked while the users are being enriched.
+func BuildClaimPendingUsersQuery(claim uuid.UUID, now time.Time, lease time.Duration, limit int) (string, []interface{}, error) {
+	next := sq.Select("id").
+		From(`"user"`).
+		Where("enrichment_status IN ('pending', 'processing')").
+		Where(sq.LtOrEq{"enrichment_next_attempt_at": now}).
+		OrderBy("enrichment_next_attempt_at", "created_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	return claimUser(sq.Expr("id IN (?)", next), claim, now, lease).ToSql()
+}
+
+// BuildClaimUserByIDQuery claims the given user, whatever its status, unless
+// a worker still holds a live lease on it.
+func BuildClaimUserByIDQuery(id uuid.UUID, claim uuid.UUID, now time.Time, lease time.Duration) (string, []interface{}, error) {
+	where := sq.And{
+		sq.Eq{"id": id},
+		sq.Or{
+			sq.NotEq{"enrichment_status": "processing"},
+			sq.LtOrEq{"enrichment_next_attempt_at": now},
+		},
+	}
+	return claimUser(where, claim, now, lease).ToSql()
+}
+
+// claimUser marks the users matching where as being processed under claim
+// until the lease ends. The attempt is counted here rather than on write-back,
+// so that a user whose worker keeps dying still runs out of attempts.
+func claimUser(where sq.Sqlizer, claim uuid.UUID, now time.Time, lease time.Duration) sq.UpdateBuilder {
+	return sq.Update(`"user"`).
+		PlaceholderFormat(sq.Dollar).
+		Set("enrichment_status", "processing").
+		Set("enrichment_claim", claim).
+		Set("enrichment_attempts", sq.Expr("enrichment_attempts + 1")).
+		Set("enrichment_next_attempt_at", now.Add(lease)).
+		Where(where).
+		Suffix(returningUserColumns)
+}
+
+// HasUserFilter reports whether the filter narrows the users down at all.
+func HasUserFilter(filter dao.UserFilterDAO) bool {
+	return len(userFilterPredicate(filter)) > 0
+}
+
+// BuildRequeueUsersQuery puts the next batch of users matching the filter,
+// in id order after the given one, back into the enrichment queue under the
+// given job and records them as the job's users. Without force, users that
+// already have every attribute are left alone. It returns the id of every
+// user in the batch, and whether it was queued, a user deleted meanwhile
+// being the only one that is not.
+func BuildRequeueUsersQuery(jobID uuid.UUID, filter dao.UserFilterDAO, force bool, now time.Time, after uuid.NullUUID, limit int) (string, []interface{}, error) {
+	predicate := userFilterPredicate(filter)
+	if !force {
+		predicate = append(predicate, sq.Or{
+			sq.Eq{"age": nil},
+			sq.Eq{"sex": nil},
+			sq.Eq{"nationality": nil},
+		})
+	}
+	if after.Valid {
+		predicate = append(predicate, sq.Gt{"id": after.UUID})
+	}
+
+	batch := sq.Select("id").
+		From(`"user"`).
+		OrderBy("id").
+		Limit(uint64(limit))
+
+	if len(predicate) > 0 {
+		batch = batch.Where(predicate)
+	}
+
+	requeue := sq.Update(`"user"`).
+		Set("enrichment_status", "pending").
+		Set("enrichment_attempts", 0).
+		Set("enrichment_error", nil).
+		Set("enrichment_next_attempt_at", now).
+		Set("enrichment_force", force).
+		Set("enrichment_job_id", jobID).
+		Set("enrichment_claim", nil).
+		Where("id IN (SELECT id FROM batch)").
+		Suffix("RETURNING id")
+
+	queue := sq.Insert("enrichment_job_user").
+		Columns("job_id", "user_id").
+		Select(sq.Select().Column(sq.Expr("?::uuid", jobID)).Column("id").From("requeued")).
+		Suffix("RETURNING user_id")
+
+	builder := sq.Select("b.id", "q.user_id IS NOT NULL").
+		Prefix("WITH batch AS (?), requeued AS (?), queued AS (?)", batch, requeue, queue).
+		From("batch b").
+		LeftJoin("queued q ON q.user_id = b.id").
+		OrderBy("b.id").
+		PlaceholderFormat(sq.Dollar)
+
+	return builder.ToSql()
+}
+
+// BuildUpdateUserEnrichmentQuery writes an enrichment result back and releases
+// the claim. It only matches while the claim is still held, so the result of
+// a worker whose lease was taken over, or whose user was requeued meanwhile,
+// is dropped. Without force found attributes only fill empty ones, which
+// keeps values set through the API while the user was being enriched.
+func BuildUpdateUserEnrichmentQuery(id uuid.UUID, claim uuid.UUID, enrichment dao.UserEnrichmentDAO) (string, []interface{}, error) {
+	builder := sq.Update(`"user"`).
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{"id": id, "enrichment_claim": claim}).
+		Set("updated_at", time.Now()).
+		Set("enrichment_status", enrichment.Status).
+		Set("enrichment_error", enrichment.Error).
+		Set("enrichment_next_attempt_at", enrichment.NextAttemptAt).
+		Set("enrichment_force", false).
+		Set("enrichment_claim", nil)
+
+	// The probability always follows its attribute, so a replaced value never
+	// keeps the confidence of the previous one.
+	if enrichment.Nationality.Valid {
+		builder = setEnriched(builder, enrichment.Force, "nationality", map[string]interface{}{
+			"nationality":             enrichment.Nationality.String,
+			"nationality_probability": enrichment.NationalityProbability,
+		})
+	}
+
+	if enrichment.Age.Valid {
+		builder = setEnriched(builder, enrichment.Force, "age", map[string]interface{}{
+			"age": enrichment.Age.Int64,
+		})
+	}
+
+	if enrichment.Sex.Valid {
+		builder = setEnriched(builder, enrichment.Force, "sex", map[string]interface{}{
+			"sex":             enrichment.Sex.String,
+			"sex_probability": enrichment.SexProbability,
+			"sex_source":      enrichment.SexSource,
+		})
+	}
+
+	if enrichment.EnrichmentSampleCount.Valid {
+		builder = builder.Set("enrichment_sample_count", enrichment.EnrichmentSampleCount.Int64)
+	}
+
+	builder = builder.Suffix(returningUserColumns)
+
+	return builder.ToSql()
+}
+
+// setEnriched sets the columns of one enriched attribute. Without force they
+// are only set while the attribute column is still NULL; SET expressions see
+// the row as it was, so the check holds for every column of the attribute.
+func setEnriched(builder sq.UpdateBuilder, force bool, attribute string, values map[string]interface{}) sq.UpdateBuilder {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	for _, column := range columns {
+		if force {
+			builder = builder.Set(column, values[column])
+			continue
+		}
+		builder = builder.Set(column, sq.Expr("CASE WHEN "+attribute+" IS NULL THEN ? ELSE "+column+" END", values[column]))
+	}
+	return builder
+}
+
 func BuildDeleteUserQuery(id uuid.UUID) (string, []interface{}, error) {
 	builder := sq.Delete(`"user"`).
 		PlaceholderFormat(sq.Dollar).
 		Where(sq.Eq{"id": id}).
-		Suffix("RETURNING *")
+		Suffix(returningUserColumns)
 
 	return builder.ToSql()
 }
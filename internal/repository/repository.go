@@ -1,6 +1,7 @@
 package repository
 
 import (
+	enrichment_repo "github.com/FlyKarlik/effectiveMobile/internal/repository/enrichment"
 	user_repo "github.com/FlyKarlik/effectiveMobile/internal/repository/user"
 	"github.com/FlyKarlik/effectiveMobile/pkg/database/postgres"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
@@ -8,6 +9,7 @@ import (
 
 type Repository struct {
 	user_repo.IUserRepository
+	enrichment_repo.IEnrichmentCacheRepository
 }
 
 type repoOptions func(r *Repository) error
@@ -25,9 +27,16 @@ func New(cfgs ...repoOptions) (*Repository, error) {
 	return os, nil
 }
 
-func WithUserRepo(logger logger.Logger, q postgres.Querier) repoOptions {
+func WithUserRepo(logger logger.Logger, q postgres.Querier, similarityThreshold float64, requeueBatchSize int) repoOptions {
 	return func(r *Repository) error {
-		r.IUserRepository = user_repo.New(logger, q)
+		r.IUserRepository = user_repo.New(logger, q, similarityThreshold, requeueBatchSize)
+		return nil
+	}
+}
+
+func WithEnrichmentCacheRepo(logger logger.Logger, q postgres.Querier) repoOptions {
+	return func(r *Repository) error {
+		r.IEnrichmentCacheRepository = enrichment_repo.New(logger, q)
 		return nil
 	}
 }
@@ -2,35 +2,108 @@ package user_repo
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
 
 	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
 	"github.com/FlyKarlik/effectiveMobile/internal/repository/dao"
 	"github.com/FlyKarlik/effectiveMobile/internal/repository/queries"
 	"github.com/FlyKarlik/effectiveMobile/pkg/database/postgres"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type IUserRepository interface {
 	CountUsers(ctx context.Context, filter domain.UserFilter) (int64, error)
-	SearchUsers(ctx context.Context, pagination domain.Pagination, filter domain.UserFilter) ([]domain.User, error)
+	EstimateUsers(ctx context.Context, filter domain.UserFilter) (int64, error)
+	SearchUsers(ctx context.Context, pagination domain.Pagination, sort domain.Sort, filter domain.UserFilter) (domain.UserPage, error)
+	GetUserByID(ctx context.Context, ID uuid.UUID) (domain.User, error)
 	CreateUser(ctx context.Context, input domain.CreateUserInput) (domain.User, error)
 	UpdateUserByID(ctx context.Context, ID uuid.UUID, input domain.UpdateUserInput) (domain.User, error)
 	DeleteUserByID(ctx context.Context, ID uuid.UUID) (domain.User, error)
+	GetNationalityCandidates(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]domain.NationalityCandidate, error)
+	ProcessPendingUsers(ctx context.Context, limit int, lease time.Duration, process func(ctx context.Context, users []domain.User) []domain.UserEnrichment) ([]domain.User, error)
+	ProcessUserByID(ctx context.Context, ID uuid.UUID, lease time.Duration, process func(ctx context.Context, user domain.User) domain.UserEnrichment) (domain.User, error)
+	CreateEnrichmentJob(ctx context.Context, input domain.EnrichUsersInput) (domain.EnrichmentJob, error)
+	GetEnrichmentJob(ctx context.Context, ID uuid.UUID) (domain.EnrichmentJob, error)
 }
 
 type userRepo struct {
-	logger logger.Logger
-	q      postgres.Querier
+	logger              logger.Logger
+	q                   postgres.Querier
+	similarityThreshold float64
+	requeueBatchSize    int
 }
 
-func New(logger logger.Logger, q postgres.Querier) IUserRepository {
+func New(logger logger.Logger, q postgres.Querier, similarityThreshold float64, requeueBatchSize int) IUserRepository {
 	return &userRepo{
-		logger: logger,
-		q:      q,
+		logger:              logger,
+		q:                   q,
+		similarityThreshold: similarityThreshold,
+		requeueBatchSize:    requeueBatchSize,
 	}
 }
 
+// querier is what the pool and a transaction have in common.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// read runs fn on the pool or, for a search by full name, in a transaction
+// with the similarity threshold applied to it.
+func (u *userRepo) read(ctx context.Context, filter dao.UserFilterDAO, fn func(q querier) error) error {
+	if !filter.Query.Valid {
+		return fn(u.q)
+	}
+	return u.readTx(ctx, filter, pgx.TxOptions{}, fn)
+}
+
+// readSnapshot runs fn in a read-only repeatable read transaction, so that
+// all of its statements see the same users.
+func (u *userRepo) readSnapshot(ctx context.Context, filter dao.UserFilterDAO, fn func(q querier) error) error {
+	return u.readTx(ctx, filter, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, fn)
+}
+
+func (u *userRepo) readTx(ctx context.Context, filter dao.UserFilterDAO, txOptions pgx.TxOptions, fn func(q querier) error) error {
+	tx, err := u.q.BeginTx(ctx, txOptions)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if filter.Query.Valid {
+		if err := u.setSimilarityThreshold(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setSimilarityThreshold makes the trigram index cut off at the configured
+// threshold for the rest of tx. The <% operator takes it from a setting, not
+// from the query.
+func (u *userRepo) setSimilarityThreshold(ctx context.Context, tx pgx.Tx) error {
+	query, args, err := queries.BuildSetSimilarityThresholdQuery(u.similarityThreshold)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+	return err
+}
+
 func (u *userRepo) CountUsers(ctx context.Context, filter domain.UserFilter) (int64, error) {
 	const layer string = "repository"
 	const method = "CountUsers"
@@ -39,8 +112,34 @@ func (u *userRepo) CountUsers(ctx context.Context, filter domain.UserFilter) (in
 
 	filterDAO := new(dao.UserFilterDAO)
 	filterDAO.FromDomain(filter)
+	filterDAO.QueryThreshold = u.similarityThreshold
+
+	var count int64
+	err := u.read(ctx, *filterDAO, func(q querier) error {
+		return u.countInto(ctx, q, *filterDAO, &count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "count", count)
+	return count, nil
+}
+
+// EstimateUsers returns the planner's estimate of how many users match the
+// filter. It costs a plan instead of a scan, so it stays cheap on filters
+// that match millions of rows, but it is only as good as the statistics.
+func (u *userRepo) EstimateUsers(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	const layer string = "repository"
+	const method = "EstimateUsers"
+
+	u.logger.Debug(layer, method, "started", "filter", filter)
+
+	filterDAO := new(dao.UserFilterDAO)
+	filterDAO.FromDomain(filter)
+	filterDAO.QueryThreshold = u.similarityThreshold
 
-	query, args, err := queries.BuildCountUsersQuery(*filterDAO)
+	query, args, err := queries.BuildEstimateUsersQuery(*filterDAO)
 	if err != nil {
 		u.logger.Error(layer, method, "failed to build query", err, "filter", filter)
 		return 0, err
@@ -48,72 +147,200 @@ func (u *userRepo) CountUsers(ctx context.Context, filter domain.UserFilter) (in
 
 	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
 
-	var count int64
-	err = u.q.QueryRow(ctx, query, args...).Scan(&count)
+	var plan []byte
+	err = u.read(ctx, *filterDAO, func(q querier) error {
+		return q.QueryRow(ctx, query, args...).Scan(&plan)
+	})
 	if err != nil {
 		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
 		return 0, err
 	}
 
-	u.logger.Debug(layer, method, "successfully completed", "count", count)
-	return count, nil
+	var explained []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explained); err != nil {
+		u.logger.Error(layer, method, "failed to decode plan", err, "plan", string(plan))
+		return 0, err
+	}
+	if len(explained) == 0 {
+		err := fmt.Errorf("empty plan")
+		u.logger.Error(layer, method, "failed to decode plan", err, "plan", string(plan))
+		return 0, err
+	}
+
+	estimate := int64(math.Round(explained[0].Plan.Rows))
+	u.logger.Debug(layer, method, "successfully completed", "estimate", estimate)
+	return estimate, nil
 }
 
-func (u *userRepo) SearchUsers(ctx context.Context, pagination domain.Pagination, filter domain.UserFilter) ([]domain.User, error) {
+// SearchUsers returns a page of users and, when more users follow, the
+// keyset of the last one so the caller can continue from there. One row more
+// than the limit is read to find out whether anything follows. An exact total
+// is counted by the same query, so it always agrees with the page; past the
+// last page, where no row carries it, it is counted in the same snapshot.
+func (u *userRepo) SearchUsers(ctx context.Context, pagination domain.Pagination, sort domain.Sort, filter domain.UserFilter) (domain.UserPage, error) {
 	const method = "SearchUsers"
 	const layer string = "repository"
 
-	u.logger.Debug(layer, method, "started", "pagination", pagination, "filter", filter)
+	u.logger.Debug(layer, method, "started", "pagination", pagination, "sort", sort, "filter", filter)
 
 	paginationDAO := new(dao.PaginationDAO)
+	sortDAO := new(dao.SortDAO)
 	filterDAO := new(dao.UserFilterDAO)
 
 	paginationDAO.FromDomain(pagination)
+	sortDAO.FromDomain(sort)
 	filterDAO.FromDomain(filter)
+	filterDAO.QueryThreshold = u.similarityThreshold
+
+	if paginationDAO.Limit.Valid {
+		paginationDAO.Limit.Int64++
+	}
 
-	query, args, err := queries.BuildSearchUsersQuery(*filterDAO, *paginationDAO)
+	query, args, err := queries.BuildSearchUsersQuery(*filterDAO, *sortDAO, *paginationDAO)
 	if err != nil {
+		if errors.Is(err, queries.ErrUnsupportedSortField) {
+			u.logger.Debug(layer, method, "unsupported sort", "sort", sort)
+			return domain.UserPage{}, errs.ErrInvalidSort
+		}
+		if errors.Is(err, queries.ErrKeysetMismatch) {
+			u.logger.Debug(layer, method, "cursor does not match sort", "sort", sort)
+			return domain.UserPage{}, errs.ErrInvalidCursor
+		}
 		u.logger.Error(layer, method, "failed to build query", err, "filter", filter, "pagination", pagination)
-		return nil, err
+		return domain.UserPage{}, err
 	}
 
 	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
 
-	rows, err := u.q.Query(ctx, query, args...)
+	read := u.read
+	if paginationDAO.WithTotal {
+		read = u.readSnapshot
+	}
+
+	var (
+		found []dao.UserDAO
+		total *int64
+	)
+	err = read(ctx, *filterDAO, func(q querier) error {
+		rows, err := q.Query(ctx, query, args...)
+		if err != nil {
+			u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var user dao.UserDAO
+			fields := userFields(&user)
+			if filterDAO.Query.Valid {
+				fields = append(fields, &user.Score)
+			}
+			if paginationDAO.WithTotal {
+				total = new(int64)
+				fields = append(fields, total)
+			}
+
+			if err := rows.Scan(fields...); err != nil {
+				u.logger.Error(layer, method, "row scan failed", err, "query", query)
+				return err
+			}
+			found = append(found, user)
+		}
+
+		if err := rows.Err(); err != nil {
+			u.logger.Error(layer, method, "rows iteration error", err)
+			return err
+		}
+		rows.Close()
+
+		if !paginationDAO.WithTotal || total != nil {
+			return nil
+		}
+
+		total = new(int64)
+		if !paginationDAO.Offset.Valid && paginationDAO.After == nil {
+			return nil
+		}
+		return u.countInto(ctx, q, *filterDAO, total)
+	})
 	if err != nil {
-		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
-		return nil, err
+		return domain.UserPage{}, err
 	}
-	defer rows.Close()
 
-	var users []domain.User
-	for rows.Next() {
-		var user dao.UserDAO
-		err := rows.Scan(
-			&user.ID,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-			&user.Name,
-			&user.Surname,
-			&user.Nationality,
-			&user.Patronymic,
-			&user.Sex,
-			&user.Age,
-		)
+	var next *domain.Keyset
+	if pagination.Limit > 0 && int64(len(found)) > pagination.Limit {
+		found = found[:pagination.Limit]
+
+		keyset, err := queries.UserKeyset(found[len(found)-1], *sortDAO)
 		if err != nil {
-			u.logger.Error(layer, method, "row scan failed", err, "query", query)
-			return nil, err
+			u.logger.Error(layer, method, "failed to build keyset", err, "sort", sort)
+			return domain.UserPage{}, err
 		}
+		result := keyset.ToDomain()
+		next = &result
+	}
+
+	users := make([]domain.User, 0, len(found))
+	for _, user := range found {
 		users = append(users, user.ToDomain())
 	}
 
-	if err := rows.Err(); err != nil {
-		u.logger.Error(layer, method, "rows iteration error", err)
-		return nil, err
+	u.logger.Debug(layer, method, "successfully completed", "users_count", len(users), "has_next", next != nil)
+	return domain.UserPage{Users: users, Total: total, Next: next}, nil
+}
+
+// countInto counts the users matching filter on q, which lets a caller count
+// within a transaction it already has open.
+func (u *userRepo) countInto(ctx context.Context, q querier, filter dao.UserFilterDAO, count *int64) error {
+	const layer string = "repository"
+	const method = "countInto"
+
+	query, args, err := queries.BuildCountUsersQuery(filter)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err, "filter", filter)
+		return err
 	}
 
-	u.logger.Debug(layer, method, "successfully completed", "users_count", len(users))
-	return users, nil
+	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	if err := q.QueryRow(ctx, query, args...).Scan(count); err != nil {
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return err
+	}
+	return nil
+}
+
+func (u *userRepo) GetUserByID(ctx context.Context, ID uuid.UUID) (domain.User, error) {
+	const layer string = "repository"
+	const method = "GetUserByID"
+
+	u.logger.Debug(layer, method, "started", "id", ID)
+
+	query, args, err := queries.BuildGetUserByIDQuery(ID)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err, "id", ID)
+		return domain.User{}, err
+	}
+
+	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	user, err := scanUser(u.q.QueryRow(ctx, query, args...))
+	if err != nil {
+		if postgres.IsNoRows(err) {
+			u.logger.Debug(layer, method, "user not found", "id", ID)
+			return domain.User{}, errs.ErrUserNotFound
+		}
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return domain.User{}, err
+	}
+
+	result := user.ToDomain()
+	u.logger.Debug(layer, method, "successfully completed", "user", result)
+	return result, nil
 }
 
 func (u *userRepo) CreateUser(ctx context.Context, input domain.CreateUserInput) (domain.User, error) {
@@ -133,20 +360,36 @@ func (u *userRepo) CreateUser(ctx context.Context, input domain.CreateUserInput)
 
 	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
 
-	var user dao.UserDAO
-	err = u.q.QueryRow(ctx, query, args...).Scan(
-		&user.ID,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.Name,
-		&user.Surname,
-		&user.Nationality,
-		&user.Patronymic,
-		&user.Sex,
-		&user.Age,
-	)
+	tx, err := u.q.Begin(ctx)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to begin transaction", err)
+		return domain.User{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := scanUser(tx.QueryRow(ctx, query, args...))
 	if err != nil {
 		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return domain.User{}, translateError(err)
+	}
+
+	if len(input.NationalityCandidates) > 0 {
+		candidatesDAO := dao.NationalityCandidatesFromDomain(user.ID, input.NationalityCandidates)
+
+		query, args, err := queries.BuildInsertNationalityCandidatesQuery(candidatesDAO)
+		if err != nil {
+			u.logger.Error(layer, method, "failed to build candidates query", err, "user_id", user.ID)
+			return domain.User{}, err
+		}
+
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			u.logger.Error(layer, method, "candidates insert failed", err, "query", query, "args", args)
+			return domain.User{}, translateError(err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		u.logger.Error(layer, method, "failed to commit transaction", err)
 		return domain.User{}, err
 	}
 
@@ -172,21 +415,14 @@ func (u *userRepo) UpdateUserByID(ctx context.Context, ID uuid.UUID, input domai
 
 	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
 
-	var user dao.UserDAO
-	err = u.q.QueryRow(ctx, query, args...).Scan(
-		&user.ID,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.Name,
-		&user.Surname,
-		&user.Nationality,
-		&user.Patronymic,
-		&user.Sex,
-		&user.Age,
-	)
+	user, err := scanUser(u.q.QueryRow(ctx, query, args...))
 	if err != nil {
+		if postgres.IsNoRows(err) {
+			u.logger.Debug(layer, method, "user not found", "id", ID)
+			return domain.User{}, errs.ErrUserNotFound
+		}
 		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
-		return domain.User{}, err
+		return domain.User{}, translateError(err)
 	}
 
 	result := user.ToDomain()
@@ -208,8 +444,441 @@ func (u *userRepo) DeleteUserByID(ctx context.Context, ID uuid.UUID) (domain.Use
 
 	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
 
-	var user dao.UserDAO
+	user, err := scanUser(u.q.QueryRow(ctx, query, args...))
+	if err != nil {
+		if postgres.IsNoRows(err) {
+			u.logger.Debug(layer, method, "user not found", "id", ID)
+			return domain.User{}, errs.ErrUserNotFound
+		}
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return domain.User{}, translateError(err)
+	}
+
+	result := user.ToDomain()
+	u.logger.Debug(layer, method, "successfully completed", "deleted_user", result)
+	return result, nil
+}
+
+func (u *userRepo) GetNationalityCandidates(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]domain.NationalityCandidate, error) {
+	const layer string = "repository"
+	const method = "GetNationalityCandidates"
+
+	u.logger.Debug(layer, method, "started", "user_ids", userIDs)
+
+	candidates := make(map[uuid.UUID][]domain.NationalityCandidate, len(userIDs))
+	if len(userIDs) == 0 {
+		return candidates, nil
+	}
+
+	query, args, err := queries.BuildGetNationalityCandidatesQuery(userIDs)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err, "user_ids", userIDs)
+		return nil, err
+	}
+
+	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	rows, err := u.q.Query(ctx, query, args...)
+	if err != nil {
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var candidate dao.NationalityCandidateDAO
+		if err := rows.Scan(&candidate.UserID, &candidate.CountryID, &candidate.Probability); err != nil {
+			u.logger.Error(layer, method, "row scan failed", err, "query", query)
+			return nil, err
+		}
+		candidates[candidate.UserID] = append(candidates[candidate.UserID], candidate.ToDomain())
+	}
+
+	if err := rows.Err(); err != nil {
+		u.logger.Error(layer, method, "rows iteration error", err)
+		return nil, err
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "users_count", len(candidates))
+	return candidates, nil
+}
+
+// ProcessPendingUsers claims up to limit users due for enrichment, lets
+// process enrich them together and writes every outcome back. The claim holds
+// for lease; users whose worker did not write back in time are claimed again
+// by another one. It returns the users it processed, none when nobody is due.
+func (u *userRepo) ProcessPendingUsers(ctx context.Context, limit int, lease time.Duration, process func(ctx context.Context, users []domain.User) []domain.UserEnrichment) ([]domain.User, error) {
+	const layer string = "repository"
+	const method = "ProcessPendingUsers"
+
+	u.logger.Debug(layer, method, "started", "limit", limit)
+
+	claim := uuid.New()
+
+	query, args, err := queries.BuildClaimPendingUsersQuery(claim, time.Now(), lease, limit)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err)
+		return nil, err
+	}
+
+	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	rows, err := u.q.Query(ctx, query, args...)
+	if err != nil {
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return nil, err
+	}
+
+	claimed, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (dao.UserDAO, error) {
+		return scanUser(row)
+	})
+	if err != nil {
+		u.logger.Error(layer, method, "row scan failed", err, "query", query)
+		return nil, err
+	}
+
+	if len(claimed) == 0 {
+		u.logger.Debug(layer, method, "no pending users")
+		return nil, nil
+	}
+
+	users := make([]domain.User, 0, len(claimed))
+	for _, user := range claimed {
+		users = append(users, user.ToDomain())
+	}
+
+	enrichments := process(ctx, users)
+
+	processed := make([]domain.User, 0, len(claimed))
+	for i, user := range claimed {
+		// A lost claim still counts: the user was handled by whoever took
+		// it over.
+		written, err := u.writeEnrichment(ctx, method, user, claim, enrichments[i])
+		if err != nil && !errors.Is(err, errClaimLost) {
+			return processed, err
+		}
+		processed = append(processed, written)
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "users", len(processed))
+	return processed, nil
+}
+
+// ProcessUserByID enriches a single user the same way, whatever its status.
+// A user that a worker is enriching right now is reported as
+// errs.ErrEnrichmentInProgress.
+func (u *userRepo) ProcessUserByID(ctx context.Context, ID uuid.UUID, lease time.Duration, process func(ctx context.Context, user domain.User) domain.UserEnrichment) (domain.User, error) {
+	const layer string = "repository"
+	const method = "ProcessUserByID"
+
+	u.logger.Debug(layer, method, "started", "id", ID)
+
+	claim := uuid.New()
+
+	query, args, err := queries.BuildClaimUserByIDQuery(ID, claim, time.Now(), lease)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err, "id", ID)
+		return domain.User{}, err
+	}
+
+	user, err := u.processUser(ctx, method, query, args, claim, process)
+	if err != nil {
+		if !errors.Is(err, errs.ErrUserNotFound) {
+			return domain.User{}, err
+		}
+		// The claim matches nothing either when the user is missing or
+		// when its lease is still held.
+		if _, err := u.GetUserByID(ctx, ID); err != nil {
+			return domain.User{}, err
+		}
+		u.logger.Debug(layer, method, "user is being enriched", "id", ID)
+		return domain.User{}, errs.ErrEnrichmentInProgress
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "user", user)
+	return user, nil
+}
+
+// CreateEnrichmentJob creates a job and queues the users matching the filter
+// under it in batches, each in a short transaction of its own, so a large job
+// never holds locks on all its users at once. Workers may start on the first
+// batches while the later ones are still being queued.
+func (u *userRepo) CreateEnrichmentJob(ctx context.Context, input domain.EnrichUsersInput) (domain.EnrichmentJob, error) {
+	const layer string = "repository"
+	const method = "CreateEnrichmentJob"
+
+	u.logger.Debug(layer, method, "started", "input", input)
+
+	filterDAO := new(dao.UserFilterDAO)
+	filterDAO.FromDomain(input.Filter)
+	filterDAO.QueryThreshold = u.similarityThreshold
+
+	if !input.All && !queries.HasUserFilter(*filterDAO) {
+		u.logger.Debug(layer, method, "no filter given", "input", input)
+		return domain.EnrichmentJob{}, errs.ErrFilterRequired
+	}
+
+	query, args, err := queries.BuildCreateEnrichmentJobQuery(input.Force)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err, "input", input)
+		return domain.EnrichmentJob{}, err
+	}
+
+	var job dao.EnrichmentJobDAO
+	if err := u.q.QueryRow(ctx, query, args...).Scan(&job.ID, &job.CreatedAt, &job.Force); err != nil {
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return domain.EnrichmentJob{}, err
+	}
+
+	now := time.Now()
+	var after uuid.NullUUID
+	for {
+		batch, queued, last, err := u.requeueUsers(ctx, job.ID, *filterDAO, input.Force, now, after)
+		if err != nil {
+			u.logger.Error(layer, method, "failed to queue users", err, "job_id", job.ID, "queued", job.Total)
+			return domain.EnrichmentJob{}, err
+		}
+
+		job.Total += queued
+		if batch < u.requeueBatchSize {
+			break
+		}
+		after = uuid.NullUUID{UUID: last, Valid: true}
+	}
+	job.Pending = job.Total
+
+	result := job.ToDomain()
+	u.logger.Debug(layer, method, "successfully completed", "job", result)
+	return result, nil
+}
+
+// requeueUsers queues the next batch of users after the given id under the
+// job. It returns the size of the batch, how many of its users were queued
+// and the last id in it.
+func (u *userRepo) requeueUsers(ctx context.Context, jobID uuid.UUID, filter dao.UserFilterDAO, force bool, now time.Time, after uuid.NullUUID) (int, int64, uuid.UUID, error) {
+	const layer string = "repository"
+	const method = "requeueUsers"
+
+	query, args, err := queries.BuildRequeueUsersQuery(jobID, filter, force, now, after, u.requeueBatchSize)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err, "job_id", jobID)
+		return 0, 0, uuid.Nil, err
+	}
+
+	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	tx, err := u.q.Begin(ctx)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to begin transaction", err)
+		return 0, 0, uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if filter.Query.Valid {
+		if err := u.setSimilarityThreshold(ctx, tx); err != nil {
+			u.logger.Error(layer, method, "failed to set similarity threshold", err)
+			return 0, 0, uuid.Nil, err
+		}
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return 0, 0, uuid.Nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var (
+		batch  int
+		queued int64
+		last   uuid.UUID
+	)
+	for rows.Next() {
+		var ok bool
+		if err := rows.Scan(&last, &ok); err != nil {
+			u.logger.Error(layer, method, "row scan failed", err, "query", query)
+			return 0, 0, uuid.Nil, err
+		}
+		batch++
+		if ok {
+			queued++
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		u.logger.Error(layer, method, "rows iteration error", err)
+		return 0, 0, uuid.Nil, translateError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		u.logger.Error(layer, method, "failed to commit transaction", err)
+		return 0, 0, uuid.Nil, err
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "job_id", jobID, "batch", batch, "queued", queued)
+	return batch, queued, last, nil
+}
+
+func (u *userRepo) GetEnrichmentJob(ctx context.Context, ID uuid.UUID) (domain.EnrichmentJob, error) {
+	const layer string = "repository"
+	const method = "GetEnrichmentJob"
+
+	u.logger.Debug(layer, method, "started", "id", ID)
+
+	query, args, err := queries.BuildGetEnrichmentJobQuery(ID)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build query", err, "id", ID)
+		return domain.EnrichmentJob{}, err
+	}
+
+	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	var job dao.EnrichmentJobDAO
 	err = u.q.QueryRow(ctx, query, args...).Scan(
+		&job.ID,
+		&job.CreatedAt,
+		&job.Force,
+		&job.Total,
+		&job.Pending,
+		&job.Done,
+		&job.Failed,
+	)
+	if err != nil {
+		if postgres.IsNoRows(err) {
+			u.logger.Debug(layer, method, "job not found", "id", ID)
+			return domain.EnrichmentJob{}, errs.ErrEnrichmentJobNotFound
+		}
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return domain.EnrichmentJob{}, err
+	}
+
+	result := job.ToDomain()
+	u.logger.Debug(layer, method, "successfully completed", "job", result)
+	return result, nil
+}
+
+// errClaimLost reports that a claimed user was claimed again or requeued
+// before its enrichment was written back, so the result was dropped.
+var errClaimLost = errors.New("enrichment claim lost")
+
+// processUser claims the row selected by claimQuery, hands it to process and
+// writes the enrichment back under the same claim. No transaction is open
+// while process runs, as it calls external services. A missing row is
+// reported as errs.ErrUserNotFound.
+func (u *userRepo) processUser(ctx context.Context, method string, claimQuery string, claimArgs []interface{}, claim uuid.UUID, process func(ctx context.Context, user domain.User) domain.UserEnrichment) (domain.User, error) {
+	const layer string = "repository"
+
+	u.logger.Debug(layer, method, "query built", "query", claimQuery, "args", claimArgs)
+
+	claimed, err := scanUser(u.q.QueryRow(ctx, claimQuery, claimArgs...))
+	if err != nil {
+		if postgres.IsNoRows(err) {
+			return domain.User{}, errs.ErrUserNotFound
+		}
+		u.logger.Error(layer, method, "query execution failed", err, "query", claimQuery, "args", claimArgs)
+		return domain.User{}, err
+	}
+
+	enrichment := process(ctx, claimed.ToDomain())
+
+	return u.writeEnrichment(ctx, method, claimed, claim, enrichment)
+}
+
+// writeEnrichment stores the enrichment of a claimed user in one short
+// transaction. A claim lost in the meantime is reported as errClaimLost,
+// together with the user as it was claimed.
+func (u *userRepo) writeEnrichment(ctx context.Context, method string, claimed dao.UserDAO, claim uuid.UUID, enrichment domain.UserEnrichment) (domain.User, error) {
+	const layer string = "repository"
+
+	enrichmentDAO := new(dao.UserEnrichmentDAO)
+	enrichmentDAO.FromDomain(enrichment)
+
+	query, args, err := queries.BuildUpdateUserEnrichmentQuery(claimed.ID, claim, *enrichmentDAO)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to build update query", err, "user_id", claimed.ID)
+		return domain.User{}, err
+	}
+
+	u.logger.Debug(layer, method, "query built", "query", query, "args", args)
+
+	tx, err := u.q.Begin(ctx)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to begin transaction", err)
+		return domain.User{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := scanUser(tx.QueryRow(ctx, query, args...))
+	if err != nil {
+		if postgres.IsNoRows(err) {
+			u.logger.Warn(layer, method, "claim lost, enrichment dropped", errClaimLost, "user_id", claimed.ID)
+			return claimed.ToDomain(), errClaimLost
+		}
+		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
+		return domain.User{}, translateError(err)
+	}
+
+	// A new nationality comes with its own candidate list, which replaces the
+	// one stored for the previous value. The list is left alone when the
+	// found nationality did not make it in.
+	if len(enrichment.NationalityCandidates) > 0 && user.Nationality.Valid && user.Nationality.String == enrichmentDAO.Nationality.String {
+		query, args, err := queries.BuildDeleteNationalityCandidatesQuery(user.ID)
+		if err != nil {
+			u.logger.Error(layer, method, "failed to build candidates delete query", err, "user_id", user.ID)
+			return domain.User{}, err
+		}
+
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			u.logger.Error(layer, method, "candidates delete failed", err, "query", query, "args", args)
+			return domain.User{}, err
+		}
+
+		candidatesDAO := dao.NationalityCandidatesFromDomain(user.ID, enrichment.NationalityCandidates)
+
+		query, args, err = queries.BuildInsertNationalityCandidatesQuery(candidatesDAO)
+		if err != nil {
+			u.logger.Error(layer, method, "failed to build candidates query", err, "user_id", user.ID)
+			return domain.User{}, err
+		}
+
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			u.logger.Error(layer, method, "candidates insert failed", err, "query", query, "args", args)
+			return domain.User{}, translateError(err)
+		}
+	}
+
+	// A final outcome settles the user in the jobs that queued it; a retry
+	// leaves them waiting.
+	if enrichment.Status != domain.PendingEnrichmentStatus {
+		query, args, err := queries.BuildResolveEnrichmentJobUsersQuery(user.ID, enrichmentDAO.Status)
+		if err != nil {
+			u.logger.Error(layer, method, "failed to build job users query", err, "user_id", user.ID)
+			return domain.User{}, err
+		}
+
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			u.logger.Error(layer, method, "job users update failed", err, "query", query, "args", args)
+			return domain.User{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		u.logger.Error(layer, method, "failed to commit transaction", err)
+		return domain.User{}, err
+	}
+
+	return user.ToDomain(), nil
+}
+
+func scanUser(row pgx.Row) (dao.UserDAO, error) {
+	var user dao.UserDAO
+	err := row.Scan(userFields(&user)...)
+	return user, err
+}
+
+// userFields lists where each of queries.userColumns is scanned to, in order.
+func userFields(user *dao.UserDAO) []interface{} {
+	return []interface{}{
 		&user.ID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
@@ -219,13 +888,27 @@ func (u *userRepo) DeleteUserByID(ctx context.Context, ID uuid.UUID) (domain.Use
 		&user.Patronymic,
 		&user.Sex,
 		&user.Age,
-	)
-	if err != nil {
-		u.logger.Error(layer, method, "query execution failed", err, "query", query, "args", args)
-		return domain.User{}, err
+		&user.SexProbability,
+		&user.SexSource,
+		&user.NationalityProbability,
+		&user.EnrichmentSampleCount,
+		&user.EnrichmentStatus,
+		&user.EnrichmentAttempts,
+		&user.EnrichmentError,
+		&user.EnrichmentJobID,
+		&user.EnrichmentForce,
+		&user.CountryHint,
 	}
+}
 
-	result := user.ToDomain()
-	u.logger.Debug(layer, method, "successfully completed", "deleted_user", result)
-	return result, nil
+func translateError(err error) error {
+	switch {
+	case postgres.IsNoRows(err):
+		return errs.ErrUserNotFound
+	case postgres.IsUniqueViolation(err):
+		return errs.ErrConflict
+	case postgres.IsConstraintViolation(err):
+		return errs.ErrUnprocessable
+	}
+	return err
 }
@@ -0,0 +1,98 @@
+package enrichment_usecase
+
+import (
+	"context"
+
+	"github.com/FlyKarlik/effectiveMobile/config"
+	"github.com/FlyKarlik/effectiveMobile/internal/domain"
+	cache_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/cache"
+	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
+	"github.com/FlyKarlik/effectiveMobile/internal/errs"
+	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+	"github.com/FlyKarlik/effectiveMobile/pkg/translit"
+)
+
+type IEnrichmentUsecase interface {
+	GetProvidersState(ctx context.Context) []domain.ProviderState
+	PurgeCache(ctx context.Context, filter domain.EnrichmentCachePurgeFilter) (domain.PurgeEnrichmentCacheResult, error)
+	WarmCache(ctx context.Context, input domain.WarmEnrichmentCacheInput) (domain.WarmEnrichmentCacheResult, error)
+}
+
+type enrichmentUsecase struct {
+	logger             logger.Logger
+	userDriver         user_drver.IUserDriver
+	enrichmentCache    cache_drver.IEnrichmentCache
+	defaultCountryHint string
+	transliteration    translit.Scheme
+}
+
+func New(logger logger.Logger, userDriver user_drver.IUserDriver, enrichmentCache cache_drver.IEnrichmentCache, cfg *config.Enrichment) IEnrichmentUsecase {
+	return &enrichmentUsecase{
+		logger:             logger,
+		userDriver:         userDriver,
+		enrichmentCache:    enrichmentCache,
+		defaultCountryHint: cfg.DefaultCountryHint,
+		transliteration:    translit.Scheme(cfg.Transliteration),
+	}
+}
+
+func (e *enrichmentUsecase) GetProvidersState(ctx context.Context) []domain.ProviderState {
+	const layer = "usecase"
+	const method = "GetProvidersState"
+
+	e.logger.Debug(layer, method, "started")
+
+	states := e.userDriver.GetProvidersState(ctx)
+
+	e.logger.Debug(layer, method, "successfully completed", "providers", len(states))
+	return states
+}
+
+func (e *enrichmentUsecase) PurgeCache(ctx context.Context, filter domain.EnrichmentCachePurgeFilter) (domain.PurgeEnrichmentCacheResult, error) {
+	const layer = "usecase"
+	const method = "PurgeCache"
+
+	e.logger.Debug(layer, method, "started", "filter", filter)
+
+	purged, err := e.enrichmentCache.PurgeCache(ctx, filter)
+	if err != nil {
+		e.logger.Error(layer, method, "failed to purge cache", err, "filter", filter)
+		return domain.PurgeEnrichmentCacheResult{}, errs.ErrUnknown
+	}
+
+	e.logger.Info(layer, method, "enrichment cache purged", "purged", purged)
+	return domain.PurgeEnrichmentCacheResult{Purged: purged}, nil
+}
+
+func (e *enrichmentUsecase) WarmCache(ctx context.Context, input domain.WarmEnrichmentCacheInput) (domain.WarmEnrichmentCacheResult, error) {
+	const layer = "usecase"
+	const method = "WarmCache"
+
+	e.logger.Debug(layer, method, "started", "names", len(input.Names))
+
+	countryHint := e.defaultCountryHint
+	if input.CountryHint != nil {
+		countryHint = *input.CountryHint
+	}
+
+	// User creation looks names up in their Latin form, so warming does too.
+	queries := make([]domain.EnrichmentQuery, 0, len(input.Names))
+	for _, name := range input.Names {
+		queries = append(queries, domain.EnrichmentQuery{
+			Name:      translit.Transliterate(e.transliteration, name),
+			CountryID: countryHint,
+		})
+	}
+
+	var result domain.WarmEnrichmentCacheResult
+	for _, complete := range user_drver.Prefetch(ctx, e.logger, e.userDriver, queries) {
+		if complete {
+			result.Warmed++
+		} else {
+			result.Failed++
+		}
+	}
+
+	e.logger.Info(layer, method, "enrichment cache warmed", "warmed", result.Warmed, "failed", result.Failed)
+	return result, nil
+}
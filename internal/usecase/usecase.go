@@ -1,14 +1,19 @@
 package usecase
 
 import (
+	"github.com/FlyKarlik/effectiveMobile/config"
+	cache_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/cache"
 	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
 	user_repo "github.com/FlyKarlik/effectiveMobile/internal/repository/user"
+	enrichment_usecase "github.com/FlyKarlik/effectiveMobile/internal/usecase/enrichment"
 	user_usecase "github.com/FlyKarlik/effectiveMobile/internal/usecase/user"
+	"github.com/FlyKarlik/effectiveMobile/pkg/cursor"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
 )
 
 type Usecase struct {
 	user_usecase.IUserUsecase
+	enrichment_usecase.IEnrichmentUsecase
 }
 
 type repoOptions func(r *Usecase) error
@@ -26,9 +31,16 @@ func New(cfgs ...repoOptions) (*Usecase, error) {
 	return os, nil
 }
 
-func WithUserUsecase(logger logger.Logger, userRepo user_repo.IUserRepository, userDriver user_drver.IUserDriver) repoOptions {
+func WithUserUsecase(logger logger.Logger, userRepo user_repo.IUserRepository, userDriver user_drver.IUserDriver, sexRulesDriver user_drver.IUserDriver, cursorSigner *cursor.Signer, cfg *config.Enrichment) repoOptions {
 	return func(r *Usecase) error {
-		r.IUserUsecase = user_usecase.New(logger, userRepo, userDriver)
+		r.IUserUsecase = user_usecase.New(logger, userRepo, userDriver, sexRulesDriver, cursorSigner, cfg)
+		return nil
+	}
+}
+
+func WithEnrichmentUsecase(logger logger.Logger, userDriver user_drver.IUserDriver, enrichmentCache cache_drver.IEnrichmentCache, cfg *config.Enrichment) repoOptions {
+	return func(r *Usecase) error {
+		r.IEnrichmentUsecase = enrichment_usecase.New(logger, userDriver, enrichmentCache, cfg)
 		return nil
 	}
 }
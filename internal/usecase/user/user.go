@@ -2,90 +2,255 @@ package user_usecase
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/FlyKarlik/effectiveMobile/config"
 	"github.com/FlyKarlik/effectiveMobile/internal/domain"
 	user_drver "github.com/FlyKarlik/effectiveMobile/internal/driver/user"
 	"github.com/FlyKarlik/effectiveMobile/internal/errs"
 	user_repo "github.com/FlyKarlik/effectiveMobile/internal/repository/user"
+	"github.com/FlyKarlik/effectiveMobile/pkg/cursor"
 	"github.com/FlyKarlik/effectiveMobile/pkg/generics"
 	"github.com/FlyKarlik/effectiveMobile/pkg/logger"
+	"github.com/FlyKarlik/effectiveMobile/pkg/translit"
 	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 )
 
 type IUserUsecase interface {
-	SearchUsers(ctx context.Context, pagination domain.Pagination, filter domain.UserFilter) generics.ItemsOutput[domain.User]
-	CreateUser(ctx context.Context, input domain.CreateUserInput) error
+	SearchUsers(ctx context.Context, pagination domain.Pagination, sort domain.Sort, filter domain.UserFilter, include domain.UserInclude) generics.ItemsOutput[domain.User]
+	GetUserByID(ctx context.Context, ID uuid.UUID, include domain.UserInclude) (domain.User, error)
+	CreateUser(ctx context.Context, input domain.CreateUserInput) (domain.User, error)
+	EnrichPendingUsers(ctx context.Context) (int, error)
+	EnrichUserByID(ctx context.Context, ID uuid.UUID, force bool) (domain.User, error)
+	EnrichUsers(ctx context.Context, input domain.EnrichUsersInput) (domain.EnrichmentJob, error)
+	GetEnrichmentJob(ctx context.Context, ID uuid.UUID) (domain.EnrichmentJob, error)
 	DeleteUserByID(ctx context.Context, ID uuid.UUID) error
-	UpdateUserByID(ctx context.Context, ID uuid.UUID, input domain.UpdateUserInput) error
+	UpdateUserByID(ctx context.Context, ID uuid.UUID, input domain.UpdateUserInput) (domain.User, error)
 }
 
 type userUsecase struct {
-	logger     logger.Logger
-	userRepo   user_repo.IUserRepository
-	userDriver user_drver.IUserDriver
+	logger             logger.Logger
+	userRepo           user_repo.IUserRepository
+	userDriver         user_drver.IUserDriver
+	sexRulesDriver     user_drver.IUserDriver
+	sexRulesMode       domain.SexRulesModeEnum
+	defaultCountryHint string
+	transliteration    translit.Scheme
+	async              bool
+	maxAttempts        int
+	retryDelay         time.Duration
+	leaseTimeout       time.Duration
+	batchSize          int
+	prefetch           bool
+	cursorSigner       *cursor.Signer
 }
 
-func New(logger logger.Logger, userRepo user_repo.IUserRepository, userDriver user_drver.IUserDriver) IUserUsecase {
+func New(logger logger.Logger, userRepo user_repo.IUserRepository, userDriver user_drver.IUserDriver, sexRulesDriver user_drver.IUserDriver, cursorSigner *cursor.Signer, cfg *config.Enrichment) IUserUsecase {
+	// Prefetched predictions only reach the single lookups through the cache,
+	// so without it every batch call would be paid for twice.
+	prefetch := slices.Contains(cfg.AgeProviders, "cache") &&
+		slices.Contains(cfg.SexProviders, "cache") &&
+		slices.Contains(cfg.NationalityProviders, "cache")
+
 	return &userUsecase{
-		logger:     logger,
-		userRepo:   userRepo,
-		userDriver: userDriver,
+		logger:             logger,
+		userRepo:           userRepo,
+		userDriver:         userDriver,
+		sexRulesDriver:     sexRulesDriver,
+		sexRulesMode:       domain.SexRulesModeEnum(cfg.SexRulesMode),
+		defaultCountryHint: cfg.DefaultCountryHint,
+		transliteration:    translit.Scheme(cfg.Transliteration),
+		async:              cfg.Async,
+		maxAttempts:        cfg.WorkerMaxAttempts,
+		retryDelay:         cfg.WorkerRetryDelay,
+		leaseTimeout:       cfg.WorkerLeaseTimeout,
+		batchSize:          cfg.WorkerBatchSize,
+		prefetch:           prefetch,
+		cursorSigner:       cursorSigner,
 	}
 }
 
-func (u *userUsecase) SearchUsers(ctx context.Context, pagination domain.Pagination, filter domain.UserFilter) generics.ItemsOutput[domain.User] {
+func (u *userUsecase) SearchUsers(ctx context.Context, pagination domain.Pagination, sort domain.Sort, filter domain.UserFilter, include domain.UserInclude) generics.ItemsOutput[domain.User] {
 	const layer = "usecase"
 	const method = "SearchUsers"
 
-	u.logger.Debug(layer, method, "started", "pagination", pagination, "filter", filter)
+	u.logger.Debug(layer, method, "started", "pagination", pagination, "sort", sort, "filter", filter, "include", include)
+
+	// Best matches first, unless the client asked for another order.
+	if filter.Query != nil && len(sort) == 0 {
+		sort = domain.Sort{{Field: "score", Desc: true}}
+	}
+
+	after, err := u.decodeCursor(pagination.Cursor, sort)
+	if err != nil {
+		u.logger.Warn(layer, method, "invalid cursor", err)
+		return generics.ItemsOutput[domain.User]{
+			Success: false,
+			Error:   errs.ErrInvalidCursor,
+		}
+	}
+	pagination.After = after
 
 	var (
-		count int64
-		data  []domain.User
+		page     domain.UserPage
+		estimate int64
 	)
 
-	g, ctx := errgroup.WithContext(ctx)
+	// The exact total comes with the page itself. An estimate is a separate
+	// query that does not have to agree with the page, so it runs alongside.
+	g, gctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
 		var err error
-		count, err = u.userRepo.CountUsers(ctx, filter)
+		page, err = u.userRepo.SearchUsers(gctx, pagination, sort, filter)
 		if err != nil {
-			u.logger.Error(layer, method, "failed to count users", err, "filter", filter)
+			u.logger.Error(layer, method, "failed to search users", err, "pagination", pagination, "sort", sort, "filter", filter)
 			return err
 		}
-		u.logger.Debug(layer, method, "users counted", "count", count)
+		u.logger.Debug(layer, method, "users fetched", "count", len(page.Users))
 		return nil
 	})
 
-	g.Go(func() error {
-		var err error
-		data, err = u.userRepo.SearchUsers(ctx, pagination, filter)
-		if err != nil {
-			u.logger.Error(layer, method, "failed to search users", err, "pagination", pagination, "filter", filter)
-			return err
-		}
-		u.logger.Debug(layer, method, "users fetched", "count", len(data))
-		return nil
-	})
+	if pagination.Count == domain.EstimateCountMode {
+		g.Go(func() error {
+			var err error
+			estimate, err = u.userRepo.EstimateUsers(gctx, filter)
+			if err != nil {
+				u.logger.Error(layer, method, "failed to estimate users", err, "filter", filter)
+				return err
+			}
+			u.logger.Debug(layer, method, "users estimated", "estimate", estimate)
+			return nil
+		})
+	}
 
 	if err := g.Wait(); err != nil {
 		u.logger.Error(layer, method, "operation failed", err)
 		return generics.ItemsOutput[domain.User]{
 			Success: false,
-			Error:   err,
+			Error:   toCustomError(err),
+		}
+	}
+
+	data, next := page.Users, page.Next
+
+	var count int64
+	switch {
+	case page.Total != nil:
+		count = *page.Total
+	case pagination.Count == domain.EstimateCountMode:
+		count = estimate
+	}
+
+	if include.NationalityCandidates {
+		if err := u.attachNationalityCandidates(ctx, data); err != nil {
+			u.logger.Error(layer, method, "failed to load nationality candidates", err)
+			return generics.ItemsOutput[domain.User]{
+				Success: false,
+				Error:   toCustomError(err),
+			}
 		}
 	}
 
-	u.logger.Debug(layer, method, "successfully completed", "total_count", count, "items_count", len(data))
+	var nextCursor string
+	if next != nil {
+		if nextCursor, err = u.encodeCursor(*next, sort); err != nil {
+			u.logger.Error(layer, method, "failed to encode cursor", err)
+			return generics.ItemsOutput[domain.User]{
+				Success: false,
+				Error:   toCustomError(err),
+			}
+		}
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "total_count", count, "count_mode", pagination.Count, "items_count", len(data), "has_next", next != nil)
 	return generics.ItemsOutput[domain.User]{
-		Success: true,
-		Total:   count,
-		Items:   data,
+		Success:        true,
+		Total:          count,
+		TotalEstimated: pagination.Count == domain.EstimateCountMode,
+		Items:          data,
+		NextCursor:     nextCursor,
+	}
+}
+
+// cursorPayload is what a pagination cursor carries. The sort is kept with the
+// keyset because the values only make sense in the order they were taken from.
+type cursorPayload struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+	ID     uuid.UUID `json:"id"`
+}
+
+func (u *userUsecase) encodeCursor(keyset domain.Keyset, sort domain.Sort) (string, error) {
+	return u.cursorSigner.Encode(cursorPayload{
+		Sort:   sortKey(sort),
+		Values: keyset.Values,
+		ID:     keyset.ID,
+	})
+}
+
+func (u *userUsecase) decodeCursor(token string, sort domain.Sort) (*domain.Keyset, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	var payload cursorPayload
+	if err := u.cursorSigner.Decode(token, &payload); err != nil {
+		return nil, err
+	}
+
+	if payload.Sort != sortKey(sort) {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	return &domain.Keyset{Values: payload.Values, ID: payload.ID}, nil
+}
+
+func sortKey(sort domain.Sort) string {
+	fields := make([]string, 0, len(sort))
+	for _, field := range sort {
+		if field.Desc {
+			fields = append(fields, "-"+field.Field)
+		} else {
+			fields = append(fields, field.Field)
+		}
 	}
+	return strings.Join(fields, ",")
+}
+
+func (u *userUsecase) GetUserByID(ctx context.Context, ID uuid.UUID, include domain.UserInclude) (domain.User, error) {
+	const layer = "usecase"
+	const method = "GetUserByID"
+
+	u.logger.Debug(layer, method, "started", "user_id", ID, "include", include)
+
+	user, err := u.userRepo.GetUserByID(ctx, ID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			u.logger.Warn(layer, method, "user not found", err, "user_id", ID)
+			return domain.User{}, errs.ErrUserNotFound
+		}
+		u.logger.Error(layer, method, "failed to get user", err, "user_id", ID)
+		return domain.User{}, toCustomError(err)
+	}
+
+	if include.NationalityCandidates {
+		users := []domain.User{user}
+		if err := u.attachNationalityCandidates(ctx, users); err != nil {
+			u.logger.Error(layer, method, "failed to load nationality candidates", err, "user_id", ID)
+			return domain.User{}, toCustomError(err)
+		}
+		user = users[0]
+	}
+
+	u.logger.Debug(layer, method, "successfully completed", "user", user)
+	return user, nil
 }
 
 func (u *userUsecase) DeleteUserByID(ctx context.Context, ID uuid.UUID) error {
@@ -96,19 +261,19 @@ func (u *userUsecase) DeleteUserByID(ctx context.Context, ID uuid.UUID) error {
 
 	deletedUser, err := u.userRepo.DeleteUserByID(ctx, ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, errs.ErrUserNotFound) {
 			u.logger.Warn(layer, method, "user not found", err, "user_id", ID)
 			return errs.ErrUserNotFound
 		}
 		u.logger.Error(layer, method, "failed to delete user", err, "user_id", ID)
-		return errs.ErrUnknown
+		return toCustomError(err)
 	}
 
 	u.logger.Debug(layer, method, "successfully deleted user", "deleted_user", deletedUser)
 	return nil
 }
 
-func (u *userUsecase) UpdateUserByID(ctx context.Context, ID uuid.UUID, input domain.UpdateUserInput) error {
+func (u *userUsecase) UpdateUserByID(ctx context.Context, ID uuid.UUID, input domain.UpdateUserInput) (domain.User, error) {
 	const layer = "usecase"
 	const method = "UpdateUserByID"
 
@@ -116,107 +281,509 @@ func (u *userUsecase) UpdateUserByID(ctx context.Context, ID uuid.UUID, input do
 
 	updatedUser, err := u.userRepo.UpdateUserByID(ctx, ID, input)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, errs.ErrUserNotFound) {
 			u.logger.Warn(layer, method, "user not found", err, "user_id", ID)
-			return errs.ErrUserNotFound
+			return domain.User{}, errs.ErrUserNotFound
 		}
 		u.logger.Error(layer, method, "failed to update user", err, "user_id", ID, "input", input)
-		return errs.ErrUnknown
+		return domain.User{}, toCustomError(err)
 	}
 
 	u.logger.Debug(layer, method, "successfully updated user", "updated_user", updatedUser)
-	return nil
+	return updatedUser, nil
 }
 
-func (u *userUsecase) CreateUser(ctx context.Context, input domain.CreateUserInput) error {
+func (u *userUsecase) CreateUser(ctx context.Context, input domain.CreateUserInput) (domain.User, error) {
 	const method = "CreateUser"
 	const layer = "usecase"
 
-	u.logger.Debug(layer, method, "started", "input", input)
+	u.logger.Debug(layer, method, "started", "input", input, "async", u.async)
+
+	if u.async {
+		input.EnrichmentStatus = domain.PendingEnrichmentStatus
+	} else {
+		input.EnrichmentStatus = domain.DoneEnrichmentStatus
+		input.EnrichmentAttempts = 1
+		if err := u.enrich(ctx, &input); err != nil {
+			u.logger.Warn(layer, method, "enrichment incomplete", err, "name", input.Name)
+			message := err.Error()
+			input.EnrichmentStatus = domain.FailedEnrichmentStatus
+			input.EnrichmentError = &message
+		}
+	}
 
-	var (
-		age         *int64
-		nationality *string
-		sex         *domain.SexEnum
-	)
+	u.logger.Debug(layer, method, "creating user in repository", "input", input)
 
-	var wg sync.WaitGroup
-	wg.Add(3)
+	createdUser, err := u.userRepo.CreateUser(ctx, input)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to create user", err, "input", input)
+		return domain.User{}, toCustomError(err)
+	}
 
-	go func() {
-		defer wg.Done()
-		u.logger.Debug(layer, method, "fetching age started", "name", input.Name)
+	u.logger.Debug(layer, method, "user created successfully", "userID", createdUser)
+	return createdUser, nil
+}
 
-		userAge, err := u.userDriver.GetUserAge(ctx, input.Name)
+// EnrichPendingUsers enriches the next users waiting in the background
+// queue. It reports how many it took, so callers know when to back off.
+func (u *userUsecase) EnrichPendingUsers(ctx context.Context) (int, error) {
+	const method = "EnrichPendingUsers"
+	const layer = "usecase"
+
+	users, err := u.userRepo.ProcessPendingUsers(ctx, u.batchSize, u.leaseTimeout, u.enrichPendingBatch)
+	if err != nil {
+		u.logger.Error(layer, method, "failed to process pending users", err)
+		return len(users), toCustomError(err)
+	}
+
+	for _, user := range users {
+		u.logger.Debug(layer, method, "pending user processed",
+			"user_id", user.ID,
+			"status", user.EnrichmentStatus,
+			"attempts", user.EnrichmentAttempts)
+	}
+	return len(users), nil
+}
+
+// EnrichUserByID re-runs enrichment for one user right away. Attributes the
+// user already has are kept unless force is set.
+func (u *userUsecase) EnrichUserByID(ctx context.Context, ID uuid.UUID, force bool) (domain.User, error) {
+	const method = "EnrichUserByID"
+	const layer = "usecase"
+
+	u.logger.Debug(layer, method, "started", "user_id", ID, "force", force)
+
+	user, err := u.userRepo.ProcessUserByID(ctx, ID, u.leaseTimeout, func(ctx context.Context, user domain.User) domain.UserEnrichment {
+		enrichment, err := u.enrichStored(ctx, user, force)
 		if err != nil {
-			u.logger.Error(layer, method, "Failed to get user age", err)
-			return
+			u.logger.Warn(layer, method, "enrichment incomplete", err, "user_id", user.ID)
+			message := err.Error()
+			enrichment.Status = domain.FailedEnrichmentStatus
+			enrichment.Error = &message
 		}
+		return enrichment
+	})
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			u.logger.Warn(layer, method, "user not found", err, "user_id", ID)
+			return domain.User{}, errs.ErrUserNotFound
+		}
+		if errors.Is(err, errs.ErrEnrichmentInProgress) {
+			u.logger.Warn(layer, method, "user is being enriched", err, "user_id", ID)
+			return domain.User{}, errs.ErrEnrichmentInProgress
+		}
+		u.logger.Error(layer, method, "failed to enrich user", err, "user_id", ID)
+		return domain.User{}, toCustomError(err)
+	}
 
-		if userAge != 0 {
-			ageVal := int64(userAge)
-			age = &ageVal
-			u.logger.Debug(layer, method, "age fetched", "name", input.Name, "age", ageVal)
-		} else {
-			u.logger.Warn(layer, method, "age not found", nil, "name", input.Name)
+	u.logger.Debug(layer, method, "successfully completed", "user", user)
+	return user, nil
+}
+
+// EnrichUsers queues the users matching the filter for the worker pool and
+// returns the job that tracks them.
+func (u *userUsecase) EnrichUsers(ctx context.Context, input domain.EnrichUsersInput) (domain.EnrichmentJob, error) {
+	const method = "EnrichUsers"
+	const layer = "usecase"
+
+	u.logger.Debug(layer, method, "started", "input", input)
+
+	job, err := u.userRepo.CreateEnrichmentJob(ctx, input)
+	if err != nil {
+		if errors.Is(err, errs.ErrFilterRequired) {
+			u.logger.Warn(layer, method, "no filter given", err, "input", input)
+			return domain.EnrichmentJob{}, errs.ErrFilterRequired
 		}
-	}()
+		u.logger.Error(layer, method, "failed to create enrichment job", err, "input", input)
+		return domain.EnrichmentJob{}, toCustomError(err)
+	}
 
-	go func() {
-		defer wg.Done()
-		u.logger.Debug(layer, method, "fetching nationality started", "name", input.Name)
+	u.logger.Info(layer, method, "enrichment job created", "job_id", job.ID, "total", job.Total, "force", job.Force)
+	return job, nil
+}
 
-		userNationality, err := u.userDriver.GetUserNationality(ctx, input.Name)
-		if err != nil {
-			u.logger.Error(layer, method, "Failed to get user nationality", err)
-			return
+func (u *userUsecase) GetEnrichmentJob(ctx context.Context, ID uuid.UUID) (domain.EnrichmentJob, error) {
+	const method = "GetEnrichmentJob"
+	const layer = "usecase"
+
+	u.logger.Debug(layer, method, "started", "job_id", ID)
+
+	job, err := u.userRepo.GetEnrichmentJob(ctx, ID)
+	if err != nil {
+		if errors.Is(err, errs.ErrEnrichmentJobNotFound) {
+			u.logger.Warn(layer, method, "enrichment job not found", err, "job_id", ID)
+			return domain.EnrichmentJob{}, errs.ErrEnrichmentJobNotFound
 		}
+		u.logger.Error(layer, method, "failed to get enrichment job", err, "job_id", ID)
+		return domain.EnrichmentJob{}, toCustomError(err)
+	}
 
-		if userNationality != "" {
-			nationality = &userNationality
-			u.logger.Debug(layer, method, "nationality fetched", "name", input.Name, "nationality", userNationality)
-		} else {
-			u.logger.Warn(layer, method, "nationality not found", nil, "name", input.Name)
+	u.logger.Debug(layer, method, "successfully completed", "job", job)
+	return job, nil
+}
+
+// enrichPendingBatch is the worker's callback for a batch of queued users.
+// Their names are looked up through the batch endpoints first, so that the
+// per-user pass is answered by the cache and a batch costs one request per
+// provider and country instead of three per user.
+func (u *userUsecase) enrichPendingBatch(ctx context.Context, users []domain.User) []domain.UserEnrichment {
+	if u.prefetch {
+		queries := make([]domain.EnrichmentQuery, 0, len(users))
+		for _, user := range users {
+			if user.EnrichmentAttempts > int64(u.maxAttempts) {
+				continue
+			}
+			queries = append(queries, domain.EnrichmentQuery{
+				Name:      translit.Transliterate(u.transliteration, user.Name),
+				CountryID: u.countryHint(domain.CreateUserInput{CountryHint: user.CountryHint}),
+			})
 		}
-	}()
+		if len(queries) > 1 {
+			user_drver.Prefetch(ctx, u.logger, u.userDriver, queries)
+		}
+	}
 
-	go func() {
-		defer wg.Done()
-		u.logger.Debug(layer, method, "fetching sex started", "name", input.Name)
+	enrichments := make([]domain.UserEnrichment, len(users))
 
-		userSex, err := u.userDriver.GetUserSex(ctx, input.Name)
-		if err != nil {
-			u.logger.Error(layer, method, "Failed to get user sex", err)
-			return
+	var wg sync.WaitGroup
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			enrichments[i] = u.enrichPending(ctx, user)
+		}()
+	}
+	wg.Wait()
+
+	return enrichments
+}
+
+// enrichPending enriches one queued user. A failed attempt is retried with a
+// doubling delay until the attempts run out. The attempt is already counted
+// by the claim, so a user whose workers kept dying before writing back is
+// failed without another try.
+func (u *userUsecase) enrichPending(ctx context.Context, user domain.User) domain.UserEnrichment {
+	const method = "enrichPending"
+	const layer = "usecase"
+
+	attempt := user.EnrichmentAttempts
+	if attempt > int64(u.maxAttempts) {
+		err := errors.New("enrichment attempts exhausted")
+		u.logger.Warn(layer, method, "enrichment abandoned, no attempts left", err, "user_id", user.ID, "attempt", attempt)
+		message := err.Error()
+		return domain.UserEnrichment{
+			Status:        domain.FailedEnrichmentStatus,
+			Error:         &message,
+			NextAttemptAt: time.Now(),
 		}
+	}
 
-		if userSex != "" {
-			sex = &userSex
-			u.logger.Debug(layer, method, "sex fetched", "name", input.Name, "sex", userSex)
-		} else {
-			u.logger.Warn(layer, method, "sex not found", nil, "name", input.Name)
+	enrichment, err := u.enrichStored(ctx, user, user.EnrichmentForce)
+	if err == nil {
+		return enrichment
+	}
+
+	message := err.Error()
+	enrichment.Error = &message
+
+	if attempt >= int64(u.maxAttempts) {
+		u.logger.Warn(layer, method, "enrichment failed, no attempts left", err, "user_id", user.ID, "attempt", attempt)
+		enrichment.Status = domain.FailedEnrichmentStatus
+		return enrichment
+	}
+
+	delay := u.retryDelay << (attempt - 1)
+	u.logger.Warn(layer, method, "enrichment failed, will retry", err, "user_id", user.ID, "attempt", attempt, "delay", delay)
+	enrichment.Status = domain.PendingEnrichmentStatus
+	enrichment.NextAttemptAt = enrichment.NextAttemptAt.Add(delay)
+	return enrichment
+}
+
+// enrichStored enriches a user that is already stored. Without force only
+// the attributes the user does not have yet are filled, so values set through
+// the API are kept; with force every found attribute replaces the stored one.
+// Attributes the providers know nothing about are never cleared.
+func (u *userUsecase) enrichStored(ctx context.Context, user domain.User, force bool) (domain.UserEnrichment, error) {
+	input := domain.CreateUserInput{
+		Name:        user.Name,
+		Surname:     user.Surname,
+		Patronymic:  user.Patronymic,
+		CountryHint: user.CountryHint,
+	}
+	err := u.enrich(ctx, &input)
+
+	enrichment := domain.UserEnrichment{
+		Status:        domain.DoneEnrichmentStatus,
+		NextAttemptAt: time.Now(),
+		Force:         force,
+	}
+
+	if user.Age == nil || force {
+		enrichment.Age = input.Age
+	}
+
+	if user.Nationality == nil || force {
+		enrichment.Nationality = input.Nationality
+		enrichment.NationalityProbability = input.NationalityProbability
+		enrichment.NationalityCandidates = input.NationalityCandidates
+	}
+
+	if user.Sex == nil || force {
+		enrichment.Sex = input.Sex
+		enrichment.SexProbability = input.SexProbability
+		enrichment.SexSource = input.SexSource
+	}
+
+	if enrichment.Age != nil || enrichment.Nationality != nil || enrichment.Sex != nil {
+		enrichment.EnrichmentSampleCount = input.EnrichmentSampleCount
+	}
+
+	return enrichment, err
+}
+
+// enrich looks up age, nationality and sex for the input and copies what was
+// found into it. A failing lookup does not stop the others; the failures are
+// returned together once all lookups are finished.
+func (u *userUsecase) enrich(ctx context.Context, input *domain.CreateUserInput) error {
+	const method = "enrich"
+	const layer = "usecase"
+
+	var (
+		agePrediction         *domain.AgePrediction
+		nationalityPrediction *domain.NationalityPrediction
+		sexPrediction         *domain.SexPrediction
+		sexSource             domain.SexSourceEnum
+
+		ageErr, nationalityErr, sexErr error
+	)
+
+	// Providers mostly return nothing for Cyrillic names, so they are asked
+	// about the Latin form; the user is still stored under the original name.
+	query := domain.EnrichmentQuery{
+		Name:      translit.Transliterate(u.transliteration, input.Name),
+		CountryID: u.countryHint(*input),
+		Surname:   input.Surname,
+	}
+	if input.Patronymic != nil {
+		query.Patronymic = *input.Patronymic
+	}
+
+	var wg sync.WaitGroup
+
+	// Without a hint the nationality result becomes the hint, so age and sex
+	// have to wait for it. With a hint all three lookups are independent.
+	if query.CountryID == "" {
+		nationalityPrediction, nationalityErr = u.fetchNationality(ctx, query.Name)
+		if nationalityPrediction != nil {
+			query.CountryID = nationalityPrediction.CountryID
 		}
+	} else {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nationalityPrediction, nationalityErr = u.fetchNationality(ctx, query.Name)
+		}()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		agePrediction, ageErr = u.fetchAge(ctx, query)
+	}()
+	go func() {
+		defer wg.Done()
+		sexPrediction, sexSource, sexErr = u.resolveSex(ctx, query)
 	}()
 
 	wg.Wait()
 
 	u.logger.Debug(layer, method, "enrichment results",
-		"age", age,
-		"nationality", nationality,
-		"sex", sex)
+		"lookup_name", query.Name,
+		"country_hint", query.CountryID,
+		"age", agePrediction,
+		"nationality", nationalityPrediction,
+		"sex", sexPrediction,
+		"sex_source", sexSource)
 
-	input.Age = age
-	input.Nationality = nationality
-	input.Sex = sex
+	applyPredictions(input, agePrediction, nationalityPrediction, sexPrediction, sexSource)
 
-	u.logger.Debug(layer, method, "creating user in repository", "input", input)
+	return errors.Join(ageErr, nationalityErr, sexErr)
+}
 
-	createdUser, err := u.userRepo.CreateUser(ctx, input)
+func (u *userUsecase) countryHint(input domain.CreateUserInput) string {
+	if input.CountryHint != nil {
+		return *input.CountryHint
+	}
+	return u.defaultCountryHint
+}
+
+func (u *userUsecase) fetchAge(ctx context.Context, query domain.EnrichmentQuery) (*domain.AgePrediction, error) {
+	const layer = "usecase"
+	const method = "fetchAge"
+
+	u.logger.Debug(layer, method, "fetching age started", "name", query.Name, "country_id", query.CountryID)
+
+	prediction, err := u.userDriver.GetUserAge(ctx, query)
+	if err != nil {
+		u.logger.Error(layer, method, "Failed to get user age", err)
+		return nil, fmt.Errorf("age: %w", err)
+	}
+
+	if prediction.Age == 0 {
+		u.logger.Warn(layer, method, "age not found", nil, "name", query.Name)
+		return nil, nil
+	}
+
+	u.logger.Debug(layer, method, "age fetched", "name", query.Name, "age", prediction.Age, "count", prediction.Count)
+	return &prediction, nil
+}
+
+func (u *userUsecase) fetchNationality(ctx context.Context, name string) (*domain.NationalityPrediction, error) {
+	const layer = "usecase"
+	const method = "fetchNationality"
+
+	u.logger.Debug(layer, method, "fetching nationality started", "name", name)
+
+	prediction, err := u.userDriver.GetUserNationality(ctx, name)
+	if err != nil {
+		u.logger.Error(layer, method, "Failed to get user nationality", err)
+		return nil, fmt.Errorf("nationality: %w", err)
+	}
+
+	if prediction.CountryID == "" {
+		u.logger.Warn(layer, method, "nationality not found", nil, "name", name)
+		return nil, nil
+	}
+
+	u.logger.Debug(layer, method, "nationality fetched", "name", name, "nationality", prediction.CountryID, "probability", prediction.Probability)
+	return &prediction, nil
+}
+
+// resolveSex consults the provider and the local rules in the order set by the
+// sex rules mode and reports which of them produced the value. A provider
+// error is only returned when the rules could not make up for it.
+func (u *userUsecase) resolveSex(ctx context.Context, query domain.EnrichmentQuery) (*domain.SexPrediction, domain.SexSourceEnum, error) {
+	mode := u.sexRulesMode
+	if u.sexRulesDriver == nil {
+		mode = domain.DisabledSexRulesMode
+	}
+
+	if mode == domain.PrimarySexRulesMode {
+		if prediction := u.fetchRulesSex(ctx, query); prediction != nil {
+			return prediction, domain.RulesSexSource, nil
+		}
+	}
+
+	prediction, err := u.fetchSex(ctx, query)
+	if prediction != nil {
+		return prediction, domain.ProviderSexSource, nil
+	}
+
+	if mode == domain.FallbackSexRulesMode {
+		if prediction := u.fetchRulesSex(ctx, query); prediction != nil {
+			return prediction, domain.RulesSexSource, nil
+		}
+	}
+
+	return nil, "", err
+}
+
+func (u *userUsecase) fetchRulesSex(ctx context.Context, query domain.EnrichmentQuery) *domain.SexPrediction {
+	const layer = "usecase"
+	const method = "fetchRulesSex"
+
+	prediction, err := u.sexRulesDriver.GetUserSex(ctx, query)
+	if err != nil {
+		u.logger.Error(layer, method, "Failed to infer user sex", err)
+		return nil
+	}
+
+	if prediction.Sex == "" {
+		u.logger.Debug(layer, method, "no rule matched", "surname", query.Surname, "patronymic", query.Patronymic)
+		return nil
+	}
+
+	u.logger.Debug(layer, method, "sex inferred", "surname", query.Surname, "patronymic", query.Patronymic, "sex", prediction.Sex)
+	return &prediction
+}
+
+func (u *userUsecase) fetchSex(ctx context.Context, query domain.EnrichmentQuery) (*domain.SexPrediction, error) {
+	const layer = "usecase"
+	const method = "fetchSex"
+
+	u.logger.Debug(layer, method, "fetching sex started", "name", query.Name, "country_id", query.CountryID)
+
+	prediction, err := u.userDriver.GetUserSex(ctx, query)
+	if err != nil {
+		u.logger.Error(layer, method, "Failed to get user sex", err)
+		return nil, fmt.Errorf("sex: %w", err)
+	}
+
+	if prediction.Sex == "" {
+		u.logger.Warn(layer, method, "sex not found", nil, "name", query.Name)
+		return nil, nil
+	}
+
+	u.logger.Debug(layer, method, "sex fetched", "name", query.Name, "sex", prediction.Sex, "probability", prediction.Probability)
+	return &prediction, nil
+}
+
+func (u *userUsecase) attachNationalityCandidates(ctx context.Context, users []domain.User) error {
+	ids := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	candidates, err := u.userRepo.GetNationalityCandidates(ctx, ids)
 	if err != nil {
-		u.logger.Error(layer, method, "failed to create user", err, "input", input)
 		return err
 	}
 
-	u.logger.Debug(layer, method, "user created successfully", "userID", createdUser)
+	for i := range users {
+		users[i].NationalityCandidates = candidates[users[i].ID]
+	}
 	return nil
 }
+
+// applyPredictions copies enrichment results into the input. The sample count
+// is the smallest count among the provider predictions that were used, so it
+// reflects the weakest piece of evidence the record is based on. Rule-based
+// results carry no statistics and are left out of it.
+func applyPredictions(input *domain.CreateUserInput, age *domain.AgePrediction, nationality *domain.NationalityPrediction, sex *domain.SexPrediction, sexSource domain.SexSourceEnum) {
+	var counts []int64
+
+	if age != nil {
+		ageVal := int64(age.Age)
+		input.Age = &ageVal
+		counts = append(counts, age.Count)
+	}
+
+	if nationality != nil {
+		input.Nationality = &nationality.CountryID
+		input.NationalityProbability = &nationality.Probability
+		input.NationalityCandidates = nationality.Candidates
+		counts = append(counts, nationality.Count)
+	}
+
+	if sex != nil {
+		input.Sex = &sex.Sex
+		input.SexSource = &sexSource
+		if sexSource == domain.ProviderSexSource {
+			input.SexProbability = &sex.Probability
+			counts = append(counts, sex.Count)
+		}
+	}
+
+	if len(counts) > 0 {
+		sampleCount := slices.Min(counts)
+		input.EnrichmentSampleCount = &sampleCount
+	}
+}
+
+func toCustomError(err error) error {
+	var customErr *errs.CustomError
+	if errors.As(err, &customErr) {
+		return customErr
+	}
+	return errs.ErrUnknown
+}
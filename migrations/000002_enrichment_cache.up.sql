@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS enrichment_cache (
+    "name" VARCHAR(100) NOT NULL,
+    country_hint VARCHAR(2) NOT NULL DEFAULT '',
+    attribute VARCHAR(20) NOT NULL CHECK (attribute IN ('AGE', 'SEX', 'NATIONALITY')),
+    "value" JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY ("name", country_hint, attribute)
+);
+
+CREATE INDEX IF NOT EXISTS enrichment_cache_expires_at_idx ON enrichment_cache (expires_at);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS enrichment_sample_count,
+    DROP COLUMN IF EXISTS nationality_probability,
+    DROP COLUMN IF EXISTS sex_probability;
+
+TRUNCATE TABLE enrichment_cache;
+
+COMMIT;
\ No newline at end of file
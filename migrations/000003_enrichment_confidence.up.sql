@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS sex_probability DOUBLE PRECISION CHECK (sex_probability >= 0 AND sex_probability <= 1),
+    ADD COLUMN IF NOT EXISTS nationality_probability DOUBLE PRECISION CHECK (nationality_probability >= 0 AND nationality_probability <= 1),
+    ADD COLUMN IF NOT EXISTS enrichment_sample_count BIGINT CHECK (enrichment_sample_count >= 0);
+
+-- Cached values were stored as bare scalars and carry no confidence data.
+TRUNCATE TABLE enrichment_cache;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,3 @@
+BEGIN;
+    DROP TABLE IF EXISTS user_nationality_candidate;
+COMMIT;
\ No newline at end of file
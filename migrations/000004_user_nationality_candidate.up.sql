@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_nationality_candidate (
+    user_id UUID NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    country_id CHAR(2) NOT NULL,
+    probability DOUBLE PRECISION NOT NULL CHECK (probability >= 0 AND probability <= 1),
+    PRIMARY KEY (user_id, country_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_nationality_candidate_country_id_idx ON user_nationality_candidate (country_id);
+
+-- Existing users only know their top country.
+INSERT INTO user_nationality_candidate (user_id, country_id, probability)
+SELECT id, nationality, nationality_probability
+FROM "user"
+WHERE nationality IS NOT NULL AND nationality_probability IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- Cached nationality predictions were stored without the candidate list.
+DELETE FROM enrichment_cache WHERE attribute = 'NATIONALITY';
+
+COMMIT;
\ No newline at end of file
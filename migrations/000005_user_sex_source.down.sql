@@ -0,0 +1,3 @@
+BEGIN;
+    ALTER TABLE "user" DROP COLUMN IF EXISTS sex_source;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS sex_source VARCHAR(10) CHECK (sex_source IN ('PROVIDER', 'RULES', 'MANUAL'));
+
+-- Only provider answers ever stored a probability; other rows stay unknown.
+UPDATE "user" SET sex_source = 'PROVIDER' WHERE sex IS NOT NULL AND sex_probability IS NOT NULL;
+
+COMMIT;
\ No newline at end of file
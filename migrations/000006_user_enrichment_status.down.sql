@@ -0,0 +1,9 @@
+BEGIN;
+    DROP INDEX IF EXISTS idx_user_enrichment_pending;
+    ALTER TABLE "user"
+        DROP COLUMN IF EXISTS enrichment_next_attempt_at,
+        DROP COLUMN IF EXISTS enrichment_country_hint,
+        DROP COLUMN IF EXISTS enrichment_error,
+        DROP COLUMN IF EXISTS enrichment_attempts,
+        DROP COLUMN IF EXISTS enrichment_status;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,18 @@
+BEGIN;
+
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS enrichment_status VARCHAR(10) NOT NULL DEFAULT 'done'
+        CHECK (enrichment_status IN ('pending', 'done', 'failed')),
+    ADD COLUMN IF NOT EXISTS enrichment_attempts INTEGER NOT NULL DEFAULT 0 CHECK (enrichment_attempts >= 0),
+    ADD COLUMN IF NOT EXISTS enrichment_error TEXT,
+    ADD COLUMN IF NOT EXISTS enrichment_country_hint CHAR(2),
+    ADD COLUMN IF NOT EXISTS enrichment_next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Existing rows were enriched synchronously; new rows always state their status.
+ALTER TABLE "user" ALTER COLUMN enrichment_status SET DEFAULT 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_user_enrichment_pending
+    ON "user" (enrichment_next_attempt_at, created_at)
+    WHERE enrichment_status = 'pending';
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+    DROP INDEX IF EXISTS idx_user_enrichment_job_id;
+    ALTER TABLE "user"
+        DROP COLUMN IF EXISTS enrichment_job_id,
+        DROP COLUMN IF EXISTS enrichment_force;
+    DROP TABLE IF EXISTS enrichment_job;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS enrichment_job (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    force BOOLEAN NOT NULL DEFAULT FALSE,
+    total INTEGER NOT NULL DEFAULT 0 CHECK (total >= 0)
+);
+
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS enrichment_force BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS enrichment_job_id UUID REFERENCES enrichment_job (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_enrichment_job_id
+    ON "user" (enrichment_job_id)
+    WHERE enrichment_job_id IS NOT NULL;
+
+COMMIT;
\ No newline at end of file
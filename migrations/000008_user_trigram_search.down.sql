@@ -0,0 +1,7 @@
+BEGIN;
+    DROP INDEX IF EXISTS idx_user_full_name_trgm;
+    DROP INDEX IF EXISTS idx_user_patronymic_trgm;
+    DROP INDEX IF EXISTS idx_user_surname_trgm;
+    DROP INDEX IF EXISTS idx_user_name_trgm;
+    DROP EXTENSION IF EXISTS pg_trgm;
+COMMIT;
\ No newline at end of file
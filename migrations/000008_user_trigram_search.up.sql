@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Serve the substring filters on single columns, which are ILIKE '%x%'.
+CREATE INDEX IF NOT EXISTS idx_user_name_trgm ON "user" USING GIN ("name" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_surname_trgm ON "user" USING GIN (surname gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_user_patronymic_trgm ON "user" USING GIN (patronymic gin_trgm_ops);
+
+-- Serves the full-name search. The expression must stay identical to the one
+-- in the search query for the planner to use it.
+CREATE INDEX IF NOT EXISTS idx_user_full_name_trgm
+    ON "user" USING GIN ((surname || ' ' || "name" || ' ' || COALESCE(patronymic, '')) gin_trgm_ops);
+
+COMMIT;
\ No newline at end of file
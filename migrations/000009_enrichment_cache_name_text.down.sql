@@ -0,0 +1,4 @@
+BEGIN;
+    DELETE FROM enrichment_cache WHERE char_length("name") > 100;
+    ALTER TABLE enrichment_cache ALTER COLUMN "name" TYPE VARCHAR(100);
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Lookup names are transliterated before they become cache keys, and GOST
+-- turns one Cyrillic letter into up to four Latin ones, so a name that fits
+-- the user table does not necessarily fit VARCHAR(100).
+ALTER TABLE enrichment_cache ALTER COLUMN "name" TYPE TEXT;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+    DROP INDEX IF EXISTS idx_user_enrichment_queue;
+    CREATE INDEX IF NOT EXISTS idx_user_enrichment_pending
+        ON "user" (enrichment_next_attempt_at, created_at)
+        WHERE enrichment_status = 'pending';
+    UPDATE "user" SET enrichment_status = 'pending' WHERE enrichment_status = 'processing';
+    ALTER TABLE "user" DROP COLUMN IF EXISTS enrichment_claim;
+    ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_enrichment_status_check;
+    ALTER TABLE "user" ADD CONSTRAINT user_enrichment_status_check
+        CHECK (enrichment_status IN ('pending', 'done', 'failed'));
+COMMIT;
\ No newline at end of file
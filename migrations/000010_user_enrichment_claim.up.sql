@@ -0,0 +1,18 @@
+BEGIN;
+
+-- A worker claims a user by switching it to processing under a claim token
+-- and a lease, enriches it with no transaction open and writes the result
+-- back only while it still holds the claim. A lease that runs out makes the
+-- user claimable again.
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_enrichment_status_check;
+ALTER TABLE "user" ADD CONSTRAINT user_enrichment_status_check
+    CHECK (enrichment_status IN ('pending', 'processing', 'done', 'failed'));
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS enrichment_claim UUID;
+
+DROP INDEX IF EXISTS idx_user_enrichment_pending;
+CREATE INDEX IF NOT EXISTS idx_user_enrichment_queue
+    ON "user" (enrichment_next_attempt_at, created_at)
+    WHERE enrichment_status IN ('pending', 'processing');
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+    ALTER TABLE enrichment_job
+        ADD COLUMN IF NOT EXISTS total INTEGER NOT NULL DEFAULT 0 CHECK (total >= 0);
+    UPDATE enrichment_job j
+        SET total = (SELECT COUNT(*) FROM enrichment_job_user i WHERE i.job_id = j.id);
+    DROP INDEX IF EXISTS idx_enrichment_job_user_pending;
+    DROP TABLE IF EXISTS enrichment_job_user;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,27 @@
+BEGIN;
+
+-- Each job keeps the outcome of every user it queued. Deriving progress from
+-- user.enrichment_job_id lost a job's users as soon as a later job took them.
+CREATE TABLE IF NOT EXISTS enrichment_job_user (
+    job_id UUID NOT NULL REFERENCES enrichment_job (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'failed')),
+    PRIMARY KEY (job_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_enrichment_job_user_pending
+    ON enrichment_job_user (user_id)
+    WHERE status = 'pending';
+
+-- Only the users still pointing at a job can be recovered.
+INSERT INTO enrichment_job_user (job_id, user_id, status)
+SELECT enrichment_job_id,
+       id,
+       CASE WHEN enrichment_status IN ('done', 'failed') THEN enrichment_status ELSE 'pending' END
+FROM "user"
+WHERE enrichment_job_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE enrichment_job DROP COLUMN IF EXISTS total;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,182 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type Settings struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before letting probes through.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of probes allowed while half-open; all of
+	// them have to succeed for the breaker to close again.
+	HalfOpenMaxRequests int
+}
+
+type Snapshot struct {
+	Name                string
+	State               State
+	ConsecutiveFailures int
+	OpenedAt            time.Time
+}
+
+type Breaker struct {
+	mu       sync.Mutex
+	name     string
+	settings Settings
+	now      func() time.Time
+
+	state               State
+	consecutiveFailures int
+	halfOpenInFlight    int
+	halfOpenSuccesses   int
+	openedAt            time.Time
+}
+
+func New(name string, settings Settings) *Breaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 1
+	}
+	if settings.HalfOpenMaxRequests <= 0 {
+		settings.HalfOpenMaxRequests = 1
+	}
+
+	return &Breaker{
+		name:     name,
+		settings: settings,
+		now:      time.Now,
+		state:    StateClosed,
+	}
+}
+
+// Allow reports whether a call may proceed. Every successful Allow must be
+// followed by exactly one of Success, Failure or Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.settings.OpenTimeout {
+			return ErrOpen
+		}
+		b.toHalfOpen()
+	case StateHalfOpen:
+		if b.halfOpenInFlight >= b.settings.HalfOpenMaxRequests {
+			return ErrOpen
+		}
+	}
+
+	if b.state == StateHalfOpen {
+		b.halfOpenInFlight++
+	}
+	return nil
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.consecutiveFailures = 0
+	case StateHalfOpen:
+		if b.halfOpenInFlight > 0 {
+			b.halfOpenInFlight--
+		}
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.settings.HalfOpenMaxRequests {
+			b.toClosed()
+		}
+	}
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.consecutiveFailures++
+		if b.consecutiveFailures >= b.settings.FailureThreshold {
+			b.toOpen()
+		}
+	case StateHalfOpen:
+		b.consecutiveFailures++
+		b.toOpen()
+	}
+}
+
+// Release gives back a permit without counting the call either way, e.g. when
+// the caller cancelled its own context.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+}
+
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state
+	if state == StateOpen && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		state = StateHalfOpen
+	}
+
+	return Snapshot{
+		Name:                b.name,
+		State:               state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		OpenedAt:            b.openedAt,
+	}
+}
+
+func (b *Breaker) toOpen() {
+	b.state = StateOpen
+	b.openedAt = b.now()
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+}
+
+func (b *Breaker) toHalfOpen() {
+	b.state = StateHalfOpen
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+}
+
+func (b *Breaker) toClosed() {
+	b.state = StateClosed
+	b.consecutiveFailures = 0
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+	b.openedAt = time.Time{}
+}
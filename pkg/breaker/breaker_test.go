@@ -0,0 +1,149 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(settings Settings) (*Breaker, *clock) {
+	c := &clock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	b := New("test", settings)
+	b.now = func() time.Time { return c.now }
+	return b, c
+}
+
+func call(t *testing.T, b *Breaker, ok bool) {
+	t.Helper()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() = %v, want nil", err)
+	}
+	if ok {
+		b.Success()
+	} else {
+		b.Failure()
+	}
+}
+
+func assertState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+	if got := b.Snapshot().State; got != want {
+		t.Fatalf("state = %s, want %s", got, want)
+	}
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(Settings{FailureThreshold: 3, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+
+	call(t, b, false)
+	call(t, b, false)
+	assertState(t, b, StateClosed)
+
+	call(t, b, false)
+	assertState(t, b, StateOpen)
+
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() = %v, want ErrOpen", err)
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(Settings{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+
+	call(t, b, false)
+	call(t, b, true)
+	call(t, b, false)
+	assertState(t, b, StateClosed)
+
+	if got := b.Snapshot().ConsecutiveFailures; got != 1 {
+		t.Fatalf("consecutive failures = %d, want 1", got)
+	}
+}
+
+func TestBreakerHalfOpenAfterTimeout(t *testing.T) {
+	b, c := newTestBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 2})
+
+	call(t, b, false)
+	c.advance(59 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() before timeout = %v, want ErrOpen", err)
+	}
+
+	c.advance(time.Second)
+	assertState(t, b, StateHalfOpen)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("first probe Allow() = %v, want nil", err)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("second probe Allow() = %v, want nil", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("third probe Allow() = %v, want ErrOpen", err)
+	}
+
+	b.Success()
+	assertState(t, b, StateHalfOpen)
+	b.Success()
+	assertState(t, b, StateClosed)
+
+	if snapshot := b.Snapshot(); snapshot.ConsecutiveFailures != 0 || !snapshot.OpenedAt.IsZero() {
+		t.Fatalf("snapshot after closing = %+v, want no failures and no open time", snapshot)
+	}
+}
+
+func TestBreakerHalfOpenFailureReopens(t *testing.T) {
+	b, c := newTestBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+
+	call(t, b, false)
+	c.advance(time.Minute)
+
+	call(t, b, false)
+	assertState(t, b, StateOpen)
+
+	if got := b.Snapshot().OpenedAt; !got.Equal(c.now) {
+		t.Fatalf("opened at = %v, want %v", got, c.now)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() after reopening = %v, want ErrOpen", err)
+	}
+}
+
+func TestBreakerReleaseFreesProbe(t *testing.T) {
+	b, c := newTestBreaker(Settings{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+
+	call(t, b, false)
+	c.advance(time.Minute)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe Allow() = %v, want nil", err)
+	}
+	b.Release()
+	assertState(t, b, StateHalfOpen)
+
+	call(t, b, true)
+	assertState(t, b, StateClosed)
+}
+
+func TestBreakerReleaseKeepsClosedCount(t *testing.T) {
+	b, _ := newTestBreaker(Settings{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+
+	call(t, b, false)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() = %v, want nil", err)
+	}
+	b.Release()
+
+	if got := b.Snapshot().ConsecutiveFailures; got != 1 {
+		t.Fatalf("consecutive failures = %d, want 1", got)
+	}
+	assertState(t, b, StateClosed)
+}
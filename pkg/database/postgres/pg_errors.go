@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	CodeUniqueViolation     = "23505"
+	CodeForeignKeyViolation = "23503"
+	CodeNotNullViolation    = "23502"
+	CodeCheckViolation      = "23514"
+	CodeStringDataTooLong   = "22001"
+	CodeInvalidTextValue    = "22P02"
+)
+
+func IsNoRows(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows)
+}
+
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, CodeUniqueViolation)
+}
+
+func IsConstraintViolation(err error) bool {
+	return hasCode(err,
+		CodeForeignKeyViolation,
+		CodeNotNullViolation,
+		CodeCheckViolation,
+		CodeStringDataTooLong,
+		CodeInvalidTextValue,
+	)
+}
+
+func hasCode(err error, codes ...string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	for _, code := range codes {
+		if pgErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -69,3 +69,17 @@ func ToNullBool(b *bool) sql.NullBool {
 	}
 	return sql.NullBool{Bool: false, Valid: false}
 }
+
+func ToNullFloat64(f *float64) sql.NullFloat64 {
+	if f != nil {
+		return sql.NullFloat64{Float64: *f, Valid: true}
+	}
+	return sql.NullFloat64{Float64: 0, Valid: false}
+}
+
+func FromNullFloat64(n sql.NullFloat64) *float64 {
+	if n.Valid {
+		return &n.Float64
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package generics
 
 type ItemsOutput[T any] struct {
-	Success bool  `json:"success"`
-	Total   int64 `json:"total,omitempty"`
-	Items   []T   `json:"items,omitempty"`
-	Error   error `json:"error,omitempty"`
+	Success        bool   `json:"success"`
+	Total          int64  `json:"total,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	Items          []T    `json:"items,omitempty"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	Error          error  `json:"error,omitempty"`
 }
 
 type ItemOutput[T any] struct {
@@ -0,0 +1,43 @@
+package httpclient
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/FlyKarlik/effectiveMobile/config"
+)
+
+type Option func(c *http.Client)
+
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *http.Client) {
+		c.Transport = rt
+	}
+}
+
+func New(cfg *config.HTTPClient, opts ...Option) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+			ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+			IdleConnTimeout:       cfg.IdleConnTimeout,
+			MaxIdleConns:          cfg.MaxIdleConns,
+			MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client
+}
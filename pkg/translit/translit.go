@@ -0,0 +1,205 @@
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Scheme string
+
+const (
+	// SchemeNone leaves text untouched.
+	SchemeNone Scheme = "none"
+	// SchemeGOST follows GOST R 52535.1-2006, the ICAO Doc 9303 compatible
+	// table used in Russian passports. Ukrainian text follows the Ukrainian
+	// national system instead (Cabinet of Ministers resolution No. 55 of
+	// 2010), the one in Ukrainian passports. Output is plain ASCII, which is
+	// what the name statistics providers know best.
+	SchemeGOST Scheme = "gost"
+	// SchemeISO9 follows ISO 9:1995 (GOST 7.79-2000 system A): one Latin
+	// letter per Cyrillic letter, with diacritics.
+	SchemeISO9 Scheme = "iso9"
+)
+
+var gostTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+
+	// Ukrainian
+	'ґ': "g", 'є': "ie", 'і': "i", 'ї': "i",
+
+	// Kazakh
+	'ә': "a", 'ғ': "g", 'қ': "k", 'ң': "n", 'ө': "o", 'ұ': "u", 'ү': "u", 'һ': "h",
+}
+
+// ukrainianTable is the Ukrainian national system. It differs from GOST
+// mainly in г, which is h, and и, which is y.
+var ukrainianTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e",
+	'є': "ie", 'ж': "zh", 'з': "z", 'и': "y", 'і': "i", 'ї': "i", 'й': "i",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "shch", 'ь': "", 'ю': "iu", 'я': "ia",
+}
+
+// ukrainianInitialTable holds the Ukrainian values used at the start of a
+// word.
+var ukrainianInitialTable = map[rune]string{
+	'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya",
+}
+
+var iso9Table = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "ë",
+	'ж': "ž", 'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "c", 'ч': "č", 'ш': "š", 'щ': "ŝ",
+	'ъ': "ʺ", 'ы': "y", 'ь': "ʹ", 'э': "è", 'ю': "û", 'я': "â",
+
+	// Ukrainian
+	'ґ': "g̀", 'є': "ê", 'і': "ì", 'ї': "ï",
+
+	// Kazakh
+	'ә': "a̋", 'ғ': "ġ", 'қ': "ķ", 'ң': "ṇ", 'ө': "ô", 'ұ': "u̇", 'ү': "ù", 'һ': "ḥ",
+}
+
+// Transliterate converts Cyrillic letters of text to Latin using the scheme.
+// Anything the scheme has no mapping for is copied as is. Capitalisation is
+// kept: a capital that expands to several letters is title-cased inside a
+// word and upper-cased when its neighbours are capitals too.
+func Transliterate(scheme Scheme, text string) string {
+	table := tableFor(scheme)
+	if table == nil || !HasCyrillic(text) {
+		return text
+	}
+
+	runes := []rune(text)
+
+	ukrainian := scheme == SchemeGOST && isUkrainian(runes)
+	if ukrainian {
+		table = ukrainianTable
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for i, r := range runes {
+		lower := unicode.ToLower(r)
+
+		// The Ukrainian apostrophe only marks a hard sound and is dropped.
+		if ukrainian && isApostropheAt(runes, i) {
+			continue
+		}
+
+		latin, ok := table[lower]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+
+		if ukrainian {
+			if initial, ok := ukrainianInitialTable[lower]; ok && isWordStart(runes, i) {
+				latin = initial
+			}
+			// зг is zgh, so that it is not read as the zh of ж.
+			if lower == 'г' && i > 0 && unicode.ToLower(runes[i-1]) == 'з' {
+				latin = "gh"
+			}
+		}
+
+		if !unicode.IsUpper(r) || latin == "" {
+			b.WriteString(latin)
+			continue
+		}
+
+		if isUpperAt(runes, i-1) || isUpperAt(runes, i+1) {
+			b.WriteString(strings.ToUpper(latin))
+			continue
+		}
+
+		first, rest := splitFirst(latin)
+		b.WriteString(strings.ToUpper(first))
+		b.WriteString(rest)
+	}
+
+	return b.String()
+}
+
+// HasCyrillic reports whether text contains at least one Cyrillic letter.
+func HasCyrillic(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUkrainian reports whether text is recognisably Ukrainian: it has a letter
+// Russian does not, or an apostrophe inside a Cyrillic word, and no letter
+// Ukrainian does not have (Kazakh shares і). Ukrainian text without any of
+// them is transliterated like Russian.
+func isUkrainian(runes []rune) bool {
+	ukrainian := false
+	for i, r := range runes {
+		switch unicode.ToLower(r) {
+		case 'і', 'ї', 'є', 'ґ':
+			ukrainian = true
+		case 'ы', 'э', 'ё', 'ъ', 'ә', 'ғ', 'қ', 'ң', 'ө', 'ұ', 'ү', 'һ':
+			return false
+		}
+		if isApostropheAt(runes, i) {
+			ukrainian = true
+		}
+	}
+	return ukrainian
+}
+
+// isApostropheAt reports whether runes[i] is an apostrophe between two
+// Cyrillic letters.
+func isApostropheAt(runes []rune, i int) bool {
+	switch runes[i] {
+	case '\'', '’', 'ʼ':
+	default:
+		return false
+	}
+	return isCyrillicAt(runes, i-1) && isCyrillicAt(runes, i+1)
+}
+
+// isWordStart reports whether runes[i] begins a word. A letter after an
+// apostrophe is inside one.
+func isWordStart(runes []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	return !unicode.IsLetter(runes[i-1]) && !isApostropheAt(runes, i-1)
+}
+
+func isCyrillicAt(runes []rune, i int) bool {
+	return i >= 0 && i < len(runes) && unicode.Is(unicode.Cyrillic, runes[i])
+}
+
+func tableFor(scheme Scheme) map[rune]string {
+	switch scheme {
+	case SchemeGOST:
+		return gostTable
+	case SchemeISO9:
+		return iso9Table
+	}
+	return nil
+}
+
+func isUpperAt(runes []rune, i int) bool {
+	return i >= 0 && i < len(runes) && unicode.IsUpper(runes[i])
+}
+
+func splitFirst(s string) (string, string) {
+	for i := range s {
+		if i > 0 {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
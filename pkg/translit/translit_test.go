@@ -0,0 +1,91 @@
+package translit
+
+import "testing"
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme Scheme
+		input  string
+		want   string
+	}{
+		// Russian
+		{name: "gost russian name", scheme: SchemeGOST, input: "Дмитрий", want: "Dmitrii"},
+		{name: "gost russian surname", scheme: SchemeGOST, input: "Щербаков", want: "Shcherbakov"},
+		{name: "gost russian patronymic", scheme: SchemeGOST, input: "Васильевич", want: "Vasilevich"},
+		{name: "gost hard sign", scheme: SchemeGOST, input: "Подъячев", want: "Podieiachev"},
+		{name: "gost yo", scheme: SchemeGOST, input: "Пётр", want: "Petr"},
+		{name: "gost kh ts", scheme: SchemeGOST, input: "Хациев", want: "Khatsiev"},
+		{name: "gost yu ya", scheme: SchemeGOST, input: "Юлия", want: "Iuliia"},
+		{name: "gost all caps", scheme: SchemeGOST, input: "ЖУКОВ", want: "ZHUKOV"},
+		{name: "iso9 russian name", scheme: SchemeISO9, input: "Дмитрий", want: "Dmitrij"},
+		{name: "iso9 russian surname", scheme: SchemeISO9, input: "Щербаков", want: "Ŝerbakov"},
+		{name: "iso9 signs", scheme: SchemeISO9, input: "Подъячев", want: "Podʺâčev"},
+		{name: "iso9 yo", scheme: SchemeISO9, input: "Фёдор", want: "Fëdor"},
+		{name: "iso9 e and yu", scheme: SchemeISO9, input: "Эдуард Юрьевич", want: "Èduard Ûrʹevič"},
+
+		// Ukrainian
+		{name: "gost ukrainian i", scheme: SchemeGOST, input: "Олексій", want: "Oleksii"},
+		{name: "gost ukrainian yi", scheme: SchemeGOST, input: "Ївга", want: "Yivha"},
+		{name: "gost ukrainian yi inside word", scheme: SchemeGOST, input: "Наїда", want: "Naida"},
+		{name: "gost ukrainian ye", scheme: SchemeGOST, input: "Євген", want: "Yevhen"},
+		{name: "gost ukrainian ye inside word", scheme: SchemeGOST, input: "Соловйєнко", want: "Soloviienko"},
+		{name: "gost ukrainian ghe", scheme: SchemeGOST, input: "Ґалаґан", want: "Galagan"},
+		{name: "gost ukrainian he and y", scheme: SchemeGOST, input: "Григорій", want: "Hryhorii"},
+		{name: "gost ukrainian yu ya", scheme: SchemeGOST, input: "Юрій Ярославович", want: "Yurii Yaroslavovych"},
+		{name: "gost ukrainian zgh", scheme: SchemeGOST, input: "Розгін", want: "Rozghin"},
+		{name: "gost ukrainian apostrophe", scheme: SchemeGOST, input: "Мар'яна", want: "Mariana"},
+		{name: "gost ukrainian typographic apostrophe", scheme: SchemeGOST, input: "Мар’ян", want: "Marian"},
+		{name: "gost ukrainian all caps", scheme: SchemeGOST, input: "ЄВГЕНІЯ", want: "YEVHENIIA"},
+		{name: "gost russian he and i unchanged", scheme: SchemeGOST, input: "Григорий", want: "Grigorii"},
+		{name: "gost kazakh i is not ukrainian", scheme: SchemeGOST, input: "Әлі Игорев", want: "Ali Igorev"},
+		{name: "iso9 ukrainian i", scheme: SchemeISO9, input: "Олексій", want: "Oleksìj"},
+		{name: "iso9 ukrainian yi", scheme: SchemeISO9, input: "Ївга", want: "Ïvga"},
+		{name: "iso9 ukrainian ye", scheme: SchemeISO9, input: "Євген", want: "Êvgen"},
+		{name: "iso9 ukrainian ghe", scheme: SchemeISO9, input: "ґава", want: "g̀ava"},
+
+		// Kazakh
+		{name: "gost kazakh schwa", scheme: SchemeGOST, input: "Әлия", want: "Aliia"},
+		{name: "gost kazakh ghayn", scheme: SchemeGOST, input: "Ғалым", want: "Galym"},
+		{name: "gost kazakh qa ng", scheme: SchemeGOST, input: "Қоңыратбаев", want: "Konyratbaev"},
+		{name: "gost kazakh o u", scheme: SchemeGOST, input: "Өмірзақ Ұлжан Үміт", want: "Omirzak Ulzhan Umit"},
+		{name: "gost kazakh ha", scheme: SchemeGOST, input: "Һәкім", want: "Hakim"},
+		{name: "iso9 kazakh schwa", scheme: SchemeISO9, input: "әлия", want: "a̋liâ"},
+		{name: "iso9 kazakh letters", scheme: SchemeISO9, input: "ғқңөұүһ", want: "ġķṇôu̇ùḥ"},
+
+		// Passthrough
+		{name: "latin untouched", scheme: SchemeGOST, input: "Dmitry", want: "Dmitry"},
+		{name: "mixed scripts", scheme: SchemeGOST, input: "Анна-Maria", want: "Anna-Maria"},
+		{name: "latin apostrophe kept", scheme: SchemeGOST, input: "O'Брайен", want: "O'Braien"},
+		{name: "none scheme", scheme: SchemeNone, input: "Дмитрий", want: "Дмитрий"},
+		{name: "unknown scheme", scheme: Scheme("bgn"), input: "Дмитрий", want: "Дмитрий"},
+		{name: "empty", scheme: SchemeGOST, input: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Transliterate(tt.scheme, tt.input); got != tt.want {
+				t.Errorf("Transliterate(%q, %q) = %q, want %q", tt.scheme, tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasCyrillic(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "Дмитрий", want: true},
+		{input: "Қайрат", want: true},
+		{input: "Dmitry", want: false},
+		{input: "Анна-Maria", want: true},
+		{input: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := HasCyrillic(tt.input); got != tt.want {
+			t.Errorf("HasCyrillic(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package validation
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/go-playground/validator/v10"
@@ -10,11 +14,18 @@ var (
 	validatePool = sync.Pool{
 		New: func() interface{} {
 			v := validator.New(validator.WithRequiredStructEnabled())
+			v.RegisterTagNameFunc(jsonTagName)
 			return v
 		},
 	}
 )
 
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
 func Get() *validator.Validate {
 	return validatePool.Get().(*validator.Validate)
 }
@@ -28,3 +39,52 @@ func Validate(s interface{}) error {
 	defer Put(v)
 	return v.Struct(s)
 }
+
+func FieldErrors(err error) ([]FieldError, bool) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, false
+	}
+
+	fieldErrs := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return fieldErrs, true
+}
+
+func message(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "iso3166_1_alpha2":
+		return "must be an ISO 3166-1 alpha-2 country code"
+	}
+	return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+}
+
+func jsonTagName(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}